@@ -1,9 +1,8 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -12,1007 +11,1206 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-	"text/template"
+	"sync"
+	"text/tabwriter"
 	"time"
 
-	"gopkg.in/yaml.v2"
-
-	"github.com/sbeliakou/check-up/modules/bash"
+	"github.com/sbeliakou/check-up/modules/checkup"
+	"github.com/sbeliakou/check-up/modules/events"
 	"github.com/sbeliakou/check-up/modules/helper"
-	"github.com/sbeliakou/check-up/modules/jUnit"
+	"github.com/sbeliakou/check-up/modules/history"
+	"github.com/sbeliakou/check-up/modules/notify"
+	"github.com/sbeliakou/check-up/modules/prometheus"
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/resolve"
+	"github.com/sbeliakou/check-up/modules/schedule"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/waivers"
 )
 
 var version string = "v0.2.7"
-var workdir string = ""
 
+// print writes the message to the console, colors and status symbols are replaced by the console writer when colors are disabled
 func print(msg string) {
-	if os.Getenv("TERM") == "" {
-		mod := regexp.MustCompile(`\033[^m]*m`).ReplaceAllString(msg, "")
-		mod = regexp.MustCompile(`✓`).ReplaceAllString(mod, "success")
-		mod = regexp.MustCompile(`✗`).ReplaceAllString(mod, "FAILURE")
-		log.Println(mod)
-	} else {
-		log.Println(msg)
+	log.Println(msg)
+}
+
+func load(tmpFile *os.File, URL string) error {
+	resp, err := http.Get(URL)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
+	_, err = io.Copy(tmpFile, resp.Body)
+	return err
 }
 
-type LoopConfig struct {
-	Items   []string `yaml:"items"`
-	Command string   `yaml:"command"`
+type reportFile struct {
+	fileName string
+	format   string
 }
 
-type suitConfig struct {
-	Name        string            `yaml:"name"`
-	FileName    string            `yaml:"filename"`
-	Cases       []ScenarioItem    `yaml:"cases"`
-	CustomIndex string            `yaml:"custom_index"`
-	Env         map[string]string `yaml:"env"`
-	EnvFiles    []string          `yaml:"envFiles"`
+func (r *reportFile) parse(d string) {
+	config := strings.SplitN(d, "=", 2)
+	if len(config) > 1 {
+		(*r).fileName = config[1]
+		(*r).format = config[0]
+	}
+}
+
+// reportFiles collects repeated '-o format=filename' options
+type reportFiles []reportFile
+
+func (r *reportFiles) String() string {
+	result := []string{}
+	for _, v := range *r {
+		result = append(result, v.format+"="+v.fileName)
+	}
+	return strings.Join(result, ",")
+}
+
+func (r *reportFiles) Set(d string) error {
+	var output reportFile
+	output.parse(d)
+
+	switch output.format {
+	case "json", "junit", "prometheus":
+	default:
+		return fmt.Errorf("unsupported report '%s', expected format=filename, where format is json, junit or prometheus", d)
+	}
+
+	*r = append(*r, output)
+	return nil
+}
 
-	startTime time.Time
-	endTime   time.Time
+var outputs reportFiles
 
-	all                  int
-	successfull          int
-	skipped              int
-	failed               int
-	score                float64
-	durationString       string
-	durationMilliSeconds int
+func jsonReportSave(reportFile string, run report.Run) {
+	os.WriteFile(reportFile, run.Marshal(), 0644)
 }
 
-type ScenarioItem struct {
-	// YAML-Defined data
-	Name        string            `yaml:"name"`
-	Case        string            `yaml:"case"`
-	Env         map[string]string `yaml:"env"`
-	EnvFiles    []string          `yaml:"envFiles"`
-	Workdir     string            `yaml:"workdir"`
-	Description string            `yaml:"description"`
-	Script      string            `yaml:"script"`
-	Skip        bool              `yaml:"skip"`
-	Output      bool              `yaml:"output"`
-	Weight      int               `yaml:"weight"`
-	Log         string            `yaml:"log"`
-	Fatal       bool              `yaml:"fatal"`
-	Before      []string          `yaml:"before"`
-	After       []string          `yaml:"after"`
-	Loop        LoopConfig        `yaml:"loop"`
-	Timeout     int               `yaml:"timeout"`
+func jUnitReportSave(reportFile string, d []*checkup.Suite) {
+	data, err := checkup.JUnit(d)
+	if err == nil {
+		err = os.WriteFile(reportFile, data, 0644)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
 
-	Debug struct {
-		Script  string `yaml:"script"`
-		Timeout int    `yaml:"timeout"`
-		stdout  string
-		result  error
-	} `yaml:"debug"`
+// compareWithBaseline loads the baseline report and prints what changed since then
+func compareWithBaseline(baselineFile string, run report.Run) {
+	baseline, err := report.Load(baselineFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Runtime data
-	status               string
-	result               error
-	stdout               string
-	durationString       string
-	durationMilliSeconds int
+	comparison := report.Compare(baseline, run)
+	comparison.File = baselineFile
+	baselineComparison = &comparison
 
-	canShow bool
-	canRun  bool
+	color := "\033[32m"
+	if comparison.RatingDelta < 0 {
+		color = "\033[31m"
+	}
 
-	env []string
+	print(fmt.Sprintf("Comparing with baseline %s:", baselineFile))
+	print(fmt.Sprintf("  rating: %.2f%% -> %.2f%% (%s%+.2f%%\033[0m)", comparison.RatingBefore, comparison.RatingAfter, color, comparison.RatingDelta))
 
-	skipReason string
+	printChanges := func(title string, mark string, changes []report.Change) {
+		print(fmt.Sprintf("  %s: %d", title, len(changes)))
+		for _, change := range changes {
+			print(fmt.Sprintf("    %s %s (%s)", mark, change.Name, change.ID))
+		}
+	}
 
-	errors []error
+	printChanges("newly failing", "\033[31m✗\033[0m", comparison.NewlyFailing)
+	printChanges("newly passing", "\033[32m✓\033[0m", comparison.NewlyPassing)
+	printChanges("added", "+", comparison.Added)
+	printChanges("removed", "-", comparison.Removed)
+	print("")
 }
 
-func (s *ScenarioItem) IsSuccessful() bool {
-	return s.status == "success"
+var (
+	localConfig               = flag.String("c", "", "Local tests case file path (Required unless -C cpecified)")
+	remoteConfig              = flag.String("C", "", "Remote tests case file url (Required unless -c specified)")
+	filter                    = flag.String("f", "", "Run tests by name regexp match")
+	wdir                      = flag.String("w", "", "Set working Dir")
+	timeout                   = flag.Int("t", 0, "Timeout of the task execution")
+	generateSampleTesCaseFile = flag.Bool("g", false, "")
+	skippedAs                 = flag.String("skipped-as", "", "Treatment of skipped cases in ratings: exclude, fail or pass")
+	controlFilter             = flag.String("control", "", "Run tests by control id glob pattern, e.g. 1.1.*")
+	tagFilter                 = flag.String("tag", "", "Run tests having any of comma separated tags")
+	listenAddress             = flag.String("listen", ":8080", "Address the serve command listens on")
+	resultsDir                = flag.String("results-dir", "", "Directory for the results of scheduled runs in serve mode")
+	retain                    = flag.Int("retain", 10, "Number of the results of scheduled runs kept per suite")
+	remediateFlag             = flag.Bool("remediate", false, "Run 'remediate' scripts of failed cases and check them again")
+	dryRun                    = flag.Bool("dry-run", false, "Show remediation scripts instead of running them")
+	interactive               = flag.Bool("interactive", false, "Ask for confirmation before every remediation")
+	waiversFile               = flag.String("waivers", "", "Waivers file with accepted failures")
+	baselineFile              = flag.String("baseline", "", "Previous JSON report to compare results with")
+	failOnRegression          = flag.Bool("fail-on-regression", false, "Exit with non-zero code when cases which passed in the baseline fail")
+	historyFlag               = flag.Bool("history", false, "Append the run results to the local history file")
+	historyFile               = flag.String("history-file", "", "Local history file path, implies --history")
+	historyLast               = flag.Int("last", 20, "Number of the latest runs analyzed by the history command")
+	historyTop                = flag.Int("top", 10, "Number of the slowest cases shown by the history command")
+	listTasks                 = flag.Bool("tasks", false, "Show before/after tasks and env in the list command")
+	listEvalLoop              = flag.Bool("eval-loop", false, "Evaluate 'loop.command' in the list command")
+	reporterName              = flag.String("reporter", "default", "Console output format: default, dots, json or github-actions")
+	colorMode                 = flag.String("color", "auto", "Colored console output: auto, always or never")
+	eventsFile                = flag.String("events", "", "Stream run events as newline delimited JSON to the file, - for stdout")
+	stream                    = flag.Bool("stream", false, "Print script output line by line while the case is running")
+	secretsStore              = flag.String("secrets", "env", "Store of {SECRET:name} references: env[:PREFIX], file:path or dir:path")
+	requireEnv                = flag.Bool("require-env", false, "Fail the cases which environment has unresolved references")
+	envPolicyFlag             = flag.String("env-policy", "", "Process environment passed to the scripts: inherit, isolated or allowlist:NAME,...")
+	updateSnapshots           = flag.Bool("update-snapshots", false, "Record the current output of 'snapshot' cases, replacing changed snapshots")
+	notifyOn                  = flag.String("notify-on", "always", "Comma separated conditions of --notify notifications: failure, change, always")
+)
+
+// notifyFlags keeps --notify values until --notify-on is parsed
+type notifyFlags []string
+
+func (n *notifyFlags) String() string {
+	return strings.Join(*n, ",")
 }
 
-func (s *ScenarioItem) IsFailed() bool {
-	return s.status == "failed"
+func (n *notifyFlags) Set(d string) error {
+	*n = append(*n, d)
+	return nil
 }
 
-func (s *ScenarioItem) CanShow() bool {
-	// return s.Case != ""
-	return s.canShow
+// listFormat is the output format of the list command
+type listFormat string
+
+func (f *listFormat) String() string {
+	return string(*f)
 }
 
-func (s *ScenarioItem) RunBash(GlobalEnv map[string]string) ([]byte, error) {
-	getIfItsGlobalEnvVar := func(envItemName string, envItemValue string) string {
-		re, _ := regexp.Compile(`^\{GLOBAL:(.*)\}$`)
-		if re.MatchString(envItemValue) {
-			matches := re.FindStringSubmatch(envItemValue)
-			if len(matches) > 1 {
-				for _, v := range os.Environ() {
-					key := strings.Split(v, "=")[0]
-					value := strings.Split(v, "=")[1]
-					if key == matches[1] {
-						return fmt.Sprintf("%s=%s", envItemName, value)
-					}
-				}
-				s.errors = append(s.errors, fmt.Errorf("there's no such variable %s", matches[1]))
-				return fmt.Sprintf("%s=", envItemName)
-			}
-		}
-		return fmt.Sprintf("%s=%s", envItemName, envItemValue)
+func (f *listFormat) Set(d string) error {
+	switch d {
+	case "table", "json", "tree":
+	default:
+		return fmt.Errorf("unsupported list format '%s', expected table, json or tree", d)
 	}
 
-	var env []string
-	for key, value := range GlobalEnv {
-		env = append(env,
-			getIfItsGlobalEnvVar(key, value),
-		)
+	*f = listFormat(d)
+	return nil
+}
+
+var listOutput = listFormat("table")
+
+var notifyValues notifyFlags
+var notifySinks []notify.Sink
+
+func init() {
+	flag.Var(&outputs, "o", "JSON, JUnit or Prometheus report file, format=filename, can be repeated")
+	flag.Var(&listOutput, "format", "Output format of the list command: table, json or tree")
+	flag.Var(&notifyValues, "notify", "Notification sink, format=url, e.g. webhook=https://example.com/hook, can be repeated")
+}
+
+var verbosity int = 0
+var command string = ""
+var activeWaivers []*waivers.Waiver
+var baselineComparison *report.Comparison
+
+// useColors is resolved from --color option and NO_COLOR environment variable
+var useColors bool = true
+
+func colorsEnabled(mode string) bool {
+	switch {
+	case mode == "never" || os.Getenv("NO_COLOR") != "":
+		return false
+	case mode == "always":
+		return true
 	}
+	term := os.Getenv("TERM")
+	return term != "" && term != "dumb" && isTerminal(os.Stdout)
+}
 
-	if len(s.EnvFiles) > 0 {
-		for _, sourceFile := range s.EnvFiles {
-			file, err := os.Open(sourceFile)
-			if err != nil {
-				s.errors = append(s.errors, err)
-				continue
-			}
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-			// Use bufio.Scanner to read the file line by line
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := scanner.Text()
-				key := strings.Split(line, "=")[0]
-				value := strings.Split(line, "=")[1]
+// consoleOutput wraps the writer when colors are disabled
+func consoleOutput(w io.Writer) io.Writer {
+	if useColors {
+		return w
+	}
+	return checkup.NoColors(w)
+}
 
-				if s.Env == nil {
-					s.Env = make(map[string]string)
-				}
-				s.Env[key] = value
-			}
+// consoleReporter, eventStream, secrets and envPolicy are set from --reporter, --events, --secrets and --env-policy options
+var consoleReporter checkup.Reporter
+var secrets resolve.Store
+var envPolicy *checkup.EnvPolicy
+var eventStream *events.Writer
+var stdoutEvents bool
+
+// runnerOptions makes the runner options from the command line
+func runnerOptions() checkup.Options {
+	return checkup.Options{
+		Workdir:         *wdir,
+		Timeout:         *timeout,
+		Verbosity:       verbosity,
+		Filter:          *filter,
+		Tags:            *tagFilter,
+		Controls:        *controlFilter,
+		SkipLoopCommand: command == "list" && !*listEvalLoop,
+		Secrets:         secrets,
+		RequireEnv:      *requireEnv,
+		EnvPolicy:       envPolicy,
+		UpdateSnapshots: *updateSnapshots,
+		SkippedAs:       *skippedAs,
+		Waivers:         activeWaivers,
+		Remediate:       command == "fix" || *remediateFlag,
+		DryRun:          *dryRun,
+		Interactive:     *interactive,
+		Reporter:        consoleReporter,
+		Output:          log.Writer(),
+		Events:          eventStream,
+		Stream:          *stream,
+		Spinner:         *stream && isTerminal(os.Stdout) && !stdoutEvents && command != "serve",
+	}
+}
 
-			// Close the file after reading
-			if err := file.Close(); err != nil {
-				fmt.Println("Error closing file:", err)
-				continue
-			}
+// listFiles finds yaml and yml files of the path, which is either a file or a directory
+func listFiles(path string) ([]string, error) {
+	var result []string
 
-			// Check for errors during line scanning
-			if err := scanner.Err(); err != nil {
-				fmt.Println("Error reading file:", err)
+	var walkDir func(dirPath string) error
+	walkDir = func(dirPath string) error {
+		entries, err := os.ReadDir(dirPath)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			fullPath := filepath.Join(dirPath, entry.Name())
+			if entry.IsDir() {
+				if err := walkDir(fullPath); err != nil {
+					return err
+				}
+			} else if filepath.Ext(fullPath) == ".yaml" || filepath.Ext(fullPath) == ".yml" {
+				result = append(result, fullPath)
 			}
 		}
+		return nil
 	}
 
-	for key, value := range s.Env {
-		env = append(env,
-			getIfItsGlobalEnvVar(key, value),
-		)
+	f, err := os.Stat(path)
+	if err != nil {
+		return nil, err
 	}
 
-	s.env = env
-
-	stdout, err := bash.RunBashScript(s.Script, workdir, s.Timeout, s.env)
-	s.stdout = strings.TrimSpace(string(stdout))
-	s.result = err
-
-	if err == nil {
-		s.status = "success"
+	if f.IsDir() {
+		if err := walkDir(path); err != nil {
+			return nil, err
+		}
 	} else {
-		s.status = "failed"
+		result = append(result, path)
+	}
 
-		if s.Debug.Script != "" {
-			debugStdout, debugErr := bash.RunBashScript(s.Debug.Script, workdir, s.Debug.Timeout, s.env)
-			s.Debug.stdout = strings.TrimSpace(string(debugStdout))
-			s.Debug.result = debugErr
-		}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("There are no yaml or yml files found in the path: %s", path)
 	}
 
-	return stdout, err
+	return result, nil
 }
 
-func (c *suitConfig) getScenarioIds() []int {
-	result := []int{}
+func main() {
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
 
-	for i := 0; i < len(c.Cases); i++ {
-		// if c.Cases[i].Skip {
-		// 	continue
-		// }
+	// Modified Args slice
+	args := os.Args[:1] // keep the program name
+	verbosity = 0
 
-		if c.Cases[i].canShow || c.Cases[i].canRun {
-			result = append(result, i)
-		}
+	argv := os.Args[1:]
+	if len(argv) > 0 && (argv[0] == "list" || argv[0] == "fix" || argv[0] == "history" || argv[0] == "serve") {
+		command = argv[0]
+		argv = argv[1:]
 	}
 
-	return result
-}
+	for _, arg := range argv {
+		if arg != "--version" {
+			matches := regexp.MustCompile(`^-v(v+)?=?(\d+)?$|^--verbosity=(\d+)$`).FindStringSubmatch(arg)
+			if len(matches) > 0 {
+				if matches[0] == "-v" && matches[1] == "" { // Handle -v, means the same as -v=1
+					verbosity = 1
+				} else if matches[1] != "" { // Handle -vv, -vvv (matches multiple 'v' after the first)
+					verbosity = len(matches[1]) + 1 // +1 because the first 'v' wasn't counted in matches[1]
+				} else if matches[2] != "" { // Handle -v=2, -v=3, etc.
+					verbosity, _ = strconv.Atoi(matches[2])
+				} else if matches[3] != "" { // Handle --verbosity=2, --verbosity=3, etc.
+					verbosity, _ = strconv.Atoi(matches[3])
+				}
 
-func (c *suitConfig) getScenarioCount() int {
-	result := 0
-	for _, i := range c.getScenarioIds() {
-		if c.Cases[i].CanShow() {
-			result++
+			} else {
+				args = append(args, arg) // include other args to be parsed by flag package
+			}
+		} else {
+			log.Println(version)
+			os.Exit(0)
 		}
 	}
 
-	return result
-}
+	os.Args = args
 
-func (c *suitConfig) getIdByName(name string) int {
-	for id, item := range c.Cases {
-		if item.Name == name {
-			return id
-		}
+	flag.Usage = helper.CustomUsage
+	flag.Parse()
+
+	if *generateSampleTesCaseFile {
+		log.Printf(helper.SampleTestFile)
+		os.Exit(0)
 	}
-	return -1
-}
 
-func (c *suitConfig) printHeader() {
-	scenariosCount := c.getScenarioCount()
-	filename := ""
-	if c.FileName != "" {
-		filename = fmt.Sprintf(", file: %s", c.FileName)
+	if *colorMode != "auto" && *colorMode != "always" && *colorMode != "never" {
+		log.Fatalf("unknown color mode '%s', expected one of: auto, always, never", *colorMode)
 	}
+	useColors = colorsEnabled(*colorMode)
+	log.SetOutput(consoleOutput(os.Stdout))
 
-	c.startTime = time.Now()
+	reporter, err := checkup.NewReporter(*reporterName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if command != "serve" && command != "list" {
+		consoleReporter = reporter
+	}
 
-	switch {
-	case scenariosCount > 1:
-		log.Printf("[ %s ], 1..%d tests%s\n", c.Name, scenariosCount, filename)
-	case scenariosCount == 1:
-		log.Printf("[ %s ], 1 test%s\n", c.Name, filename)
-	case scenariosCount == 0:
-		log.Printf("[ %s ], no tests to run%s\n", c.Name, filename)
-	}
-}
-
-func (c *suitConfig) signOff() {
-	c.endTime = time.Now()
-
-	sum := 0
-	max := 0
-	skipped := 0
-	failed := 0
-	all := 0
-
-	for _, i := range c.getScenarioIds() {
-		item := c.Cases[i]
-		if item.CanShow() {
-			all++
-			if item.Skip {
-				skipped++
-			} else {
-				max += item.Weight
-				if item.IsSuccessful() {
-					sum += item.Weight
-				} else {
-					failed++
-				}
-			}
-		}
+	if secrets, err = resolve.NewStore(*secretsStore); err != nil {
+		log.Fatal(err)
 	}
 
-	c.successfull = all - skipped - failed
-	c.skipped = skipped
-	c.failed = failed
-	c.all = all
-	c.score = 100 * float64(sum) / float64(max)
-	c.durationString, c.durationMilliSeconds = duration(c.startTime, c.endTime)
-}
+	if *envPolicyFlag != "" {
+		if envPolicy, err = checkup.ParseEnvPolicy(*envPolicyFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-func (c *suitConfig) printSummary() {
-	if c.all > 0 {
-		failed := fmt.Sprintf("%d tests failed", c.failed)
-		if c.failed > 0 {
-			failed = fmt.Sprintf("\033[31m%s\033[0m", failed)
+	if command != "list" {
+		eventsOutputs := []io.Writer{}
+		if *eventsFile != "" && *eventsFile != "-" {
+			f, err := os.Create(*eventsFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			eventsOutputs = append(eventsOutputs, f)
 		}
 
-		skipped := fmt.Sprintf("%d tests skipped", c.skipped)
-		if c.skipped > 0 {
-			skipped = fmt.Sprintf("\033[36m%s\033[0m", skipped)
+		// events and json reporter own stdout, the rest of the output goes to stderr
+		if *eventsFile == "-" || (*reporterName == "json" && command != "serve") {
+			eventsOutputs = append(eventsOutputs, os.Stdout)
+			log.SetOutput(consoleOutput(os.Stderr))
+			stdoutEvents = true
 		}
 
-		if c.failed > 0 {
-			print(fmt.Sprintf("%d (of %d) tests passed, %s, %s, rated as %.2f%%, spent %s", c.successfull, c.all, failed, skipped, c.score, c.durationString))
-		} else {
-			print(fmt.Sprintf("\033[32m%d (of %d) tests passed, %s, %s, rated as %.2f%%, spent %s\033[0m", c.successfull, c.all, failed, skipped, c.score, c.durationString))
+		if len(eventsOutputs) > 0 {
+			eventStream = events.NewWriter(io.MultiWriter(eventsOutputs...))
 		}
 	}
 
-	print("")
-}
-
-type taskScriptDetails struct {
-	Name    string
-	Script  string
-	Stdout  string
-	Result  error
-	Timeout int
-	Env     []string
-	Errors  []error
-}
+	if err := scoring.ValidateSkipped(*skippedAs); err != nil {
+		log.Fatal(err)
+	}
 
-func printOut(b string, t []taskScriptDetails, indent ...int) {
-	indentStr := "     "
-	if len(t) > 0 {
-		log.Println(indentStr[2:] + b)
+	if *historyFile == "" {
+		*historyFile = history.DefaultFile()
 	} else {
-		log.Println(indentStr[2:] + b + " not defined\n")
-		return
+		*historyFlag = true
 	}
 
-	if len(indent) > 0 {
-		indentStr = indentStr + strings.Repeat(" ", indent[0])
+	if command == "history" {
+		showHistory(*historyFile, *historyLast, *historyTop)
+		return
 	}
 
-	for _, item := range t {
-		if item.Name != "" {
-			log.Printf(indentStr[2:]+"%s", item.Name)
+	if *waiversFile != "" {
+		activeWaivers, err = waivers.Load(*waiversFile, time.Now())
+		if err != nil {
+			log.Fatal(err)
 		}
+	}
 
-		if len(item.Script) > 0 {
-			log.Println(indentStr + "script: |\n  " + indentStr + regexp.MustCompile(`\n`).ReplaceAllString(item.Script, "\n  "+indentStr))
+	for _, value := range notifyValues {
+		sink, err := notify.Parse(value, strings.Split(*notifyOn, ","))
+		if err != nil {
+			log.Fatal(err)
 		}
+		notifySinks = append(notifySinks, sink)
+	}
 
-		if len(item.Stdout) == 0 {
-			log.Println(indentStr + "stdout: \"\" (output is empty)")
-		} else {
-			log.Println(indentStr + "stdout: |\n  " + indentStr + regexp.MustCompile(`\n`).ReplaceAllString(item.Stdout, "\n  "+indentStr))
-		}
+	if command == "serve" {
+		serve(*listenAddress)
+		return
+	}
 
-		if item.Timeout != 0 {
-			log.Printf(indentStr+"timeout: %d sec", item.Timeout)
-		} else {
-			log.Printf(indentStr + "timeout: not defined")
-		}
+	runner := checkup.NewRunner(runnerOptions())
+	d, err := loadSuites(runner)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		exitCodeInt := 0
-		if item.Result != nil {
-			exitCodeInt, _ = strconv.Atoi(regexp.MustCompile(`exit status (\d+)`).FindStringSubmatch(item.Result.Error())[1])
-		}
+	if len(d) > 0 && command == "list" {
+		listScenarios(os.Stdout, d, string(listOutput), *listTasks)
+		return
+	}
+
+	if len(d) > 0 {
+		runner.Run(d)
 
-		color := "\033[31m"
-		if exitCodeInt == 0 {
-			color = "\033[32m"
+		if *baselineFile != "" {
+			compareWithBaseline(*baselineFile, runner.Report(d))
 		}
-		log.Printf(indentStr+"exit code: %d (%s%s\033[0m)", exitCodeInt, color, bash.ExplainExitCode(exitCodeInt))
 
-		if len(item.Env) > 0 {
-			log.Println(indentStr + "environment:")
-			for _, v := range item.Env {
-				log.Println(indentStr + "  " + v)
-			}
+		handleReports(runner, d)
+
+		if len(notifySinks) > 0 || hasSuiteNotifications(d) {
+			sendNotifications(runner, d, previousRun(), log.Default())
 		}
 
-		if len(item.Errors) > 0 {
-			log.Println(indentStr + "warnings:")
-			for _, v := range item.Errors {
-				log.Println(indentStr + "  " + v.Error())
+		if *historyFlag {
+			if err := history.Append(*historyFile, history.NewEntry(runner.Report(d), time.Now())); err != nil {
+				log.Println(err)
 			}
 		}
 
-		log.Println()
+		if *failOnRegression && baselineComparison != nil && baselineComparison.Regressions() > 0 {
+			os.Exit(1)
+		}
+	} else {
+		flag.Usage()
 	}
 }
 
-func (c *suitConfig) printTestStatus(id int, asId ...int) {
-	testCase := c.Cases[id]
-
-	status, color := "✗", "\033[31m" // Assume failure
-
-	if testCase.IsSuccessful() {
-		status, color = "✓", "\033[32m"
-	}
-
-	if testCase.Skip {
-		status, color = "-", "\033[36m"
+// loadSuites reads the suites from -c path (file or directory) or -C url, and expands them with the runner
+func loadSuites(r *checkup.Runner) ([]*checkup.Suite, error) {
+	d := []*checkup.Suite{}
+	if *localConfig != "" {
+		files, err := listFiles(*localConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if len(file) > 0 {
+				suite, err := checkup.LoadSuiteFile(file)
+				if err != nil {
+					return nil, err
+				}
+				cwdir, _ := os.Getwd()
+				suite.FileName = strings.Replace(file, cwdir, ".", 1)
+				d = append(d, r.Expand(suite))
+			}
+		}
 	}
 
-	i := id
-	if len(asId) > 0 {
-		i = asId[0]
-	}
+	if *remoteConfig != "" {
+		tmpDir, err := os.MkdirTemp("/var/tmp", ".")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create a temporary directory: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
 
-	caseStatusMsg := ""
-	if c.CustomIndex != "" {
-		dataFuncMap := template.FuncMap{
-			"add": func(x, y int) int { return x + y },
+		tmpFile, err := os.CreateTemp(tmpDir, "tmp.*")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create a temporary file: %v", err)
 		}
+		defer tmpFile.Close()
 
-		data := map[string]interface{}{
-			"TaskId":    i - 1,
-			"TaskCount": c.getScenarioCount(),
+		config := tmpFile.Name()
+		if matched, _ := regexp.MatchString("^http(s)?://", *remoteConfig); matched {
+			if err := load(tmpFile, *remoteConfig); err != nil {
+				return nil, err
+			}
 		}
 
-		tmpl, err := template.New("custom_index").Funcs(dataFuncMap).Parse(c.CustomIndex)
+		suite, err := checkup.LoadSuiteFile(config)
 		if err != nil {
-			panic(err)
+			return nil, errors.New(strings.Replace(err.Error(), config, *remoteConfig, 1))
 		}
+		// the suite is known by its url, the temporary file is gone after the run
+		suite.FileName = *remoteConfig
+		d = append(d, r.Expand(suite))
+	}
 
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, data)
-		if err != nil {
-			panic(err)
+	return d, nil
+}
+
+// listScenarios writes the cases of the suites to w as a table, json or tree
+func listScenarios(w io.Writer, d []*checkup.Suite, format string, withTasks bool) {
+	items := []checkup.Item{}
+	for _, c := range d {
+		items = append(items, c.Items(withTasks)...)
+	}
+
+	describe := func(item checkup.Item) string {
+		details := []string{fmt.Sprintf("weight: %d", item.Weight)}
+		if item.Control != "" {
+			details = append(details, fmt.Sprintf("control: %s", item.Control))
 		}
-		caseStatusMsg = fmt.Sprintf("%s %s", buf.String(), testCase.Case)
-	} else {
-		if testCase.Case != "" {
-			caseStatusMsg = fmt.Sprintf("%2d/%d  %s", i, c.getScenarioCount(), testCase.Case)
-		} else {
-			caseStatusMsg = fmt.Sprintf("%2s/%s  %s", "-", "-", "Silent task, not scored")
+		if len(item.Tags) > 0 {
+			details = append(details, fmt.Sprintf("tags: %s", strings.Join(item.Tags, ", ")))
+		}
+		if item.Skip {
+			details = append(details, "skipped")
 		}
+		if item.Loop != "" {
+			details = append(details, "loop command not evaluated")
+		}
+		return strings.Join(details, ", ")
 	}
 
-	if testCase.Skip {
-		caseStatusMsg = fmt.Sprintf("%s%s %s, skipping reason: %s \033[0m", color, status, caseStatusMsg, testCase.skipReason)
-	} else {
-		caseStatusMsg = fmt.Sprintf("%s%s %s, %s\033[0m", color, status, caseStatusMsg, testCase.durationString)
+	sortedEnv := func(env map[string]string) []string {
+		result := []string{}
+		for k, v := range env {
+			result = append(result, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(result)
+		return result
 	}
 
-	for _, j := range c.getScenarioIds() {
-		if j == id {
-			if testCase.CanShow() || (verbosity >= 3) {
-				log.Print(caseStatusMsg)
-
-				if testCase.Skip {
-					return
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(items)
+
+	case "tree":
+		for n, c := range d {
+			fmt.Fprintf(w, "%s [ %s ]\n", c.FileName, c.Name)
+			suiteItems := c.Items(withTasks)
+			for i, item := range suiteItems {
+				branch, indent := "├── ", "│   "
+				if i == len(suiteItems)-1 {
+					branch, indent = "└── ", "    "
 				}
+				fmt.Fprintf(w, "%s%s %s (%s)\n", branch, item.Index, item.Case, describe(item))
 
-				mainScriptLog := []taskScriptDetails{
-					{
-						Script:  strings.TrimSpace(testCase.Script),
-						Stdout:  strings.TrimSpace(testCase.stdout),
-						Result:  testCase.result,
-						Timeout: testCase.Timeout,
-						Env:     testCase.env,
-						Errors:  testCase.errors,
-					},
+				details := []string{}
+				if item.Loop != "" {
+					details = append(details, fmt.Sprintf("loop.command: %s", item.Loop))
 				}
-
-				debugScriptLog := []taskScriptDetails{}
-				if len(strings.TrimSpace(testCase.Debug.Script)) > 0 {
-					debugScriptLog = []taskScriptDetails{
-						{
-							Script:  strings.TrimSpace(testCase.Debug.Script),
-							Stdout:  strings.TrimSpace(testCase.Debug.stdout),
-							Result:  testCase.Debug.result,
-							Timeout: testCase.Debug.Timeout,
-							Errors:  testCase.errors,
-						},
-					}
+				for _, name := range item.Before {
+					details = append(details, fmt.Sprintf("before: %s", name))
 				}
-
-				beforeScriptsLog := []taskScriptDetails{}
-				for i, name := range testCase.Before {
-					beforeScriptsLog = append(beforeScriptsLog, taskScriptDetails{
-						Name:    fmt.Sprintf("%d/%d: %s", i+1, len(testCase.Before), strings.TrimSpace(c.Cases[c.getIdByName(name)].Name)),
-						Script:  strings.TrimSpace(c.Cases[c.getIdByName(name)].Script),
-						Stdout:  strings.TrimSpace(c.Cases[c.getIdByName(name)].stdout),
-						Result:  c.Cases[c.getIdByName(name)].result,
-						Timeout: c.Cases[c.getIdByName(name)].Timeout,
-						Errors:  c.Cases[c.getIdByName(name)].errors,
-					})
+				for _, name := range item.After {
+					details = append(details, fmt.Sprintf("after: %s", name))
 				}
-
-				afterScriptsLog := []taskScriptDetails{}
-				for i, name := range testCase.After {
-					afterScriptsLog = append(afterScriptsLog, taskScriptDetails{
-						Name:    fmt.Sprintf("%d/%d: %s", i+1, len(testCase.After), strings.TrimSpace(c.Cases[c.getIdByName(name)].Name)),
-						Script:  strings.TrimSpace(c.Cases[c.getIdByName(name)].Script),
-						Stdout:  strings.TrimSpace(c.Cases[c.getIdByName(name)].stdout),
-						Result:  c.Cases[c.getIdByName(name)].result,
-						Timeout: c.Cases[c.getIdByName(name)].Timeout,
-						Errors:  c.Cases[c.getIdByName(name)].errors,
-					})
+				for _, v := range sortedEnv(item.Env) {
+					details = append(details, fmt.Sprintf("env: %s", v))
 				}
-
-				switch verbosity {
-				case 1:
-					if testCase.IsFailed() {
-						printOut("case task:", mainScriptLog)
-					}
-				case 2:
-					if testCase.IsFailed() {
-						printOut("case task:", mainScriptLog)
-						printOut("debug:", debugScriptLog)
-					}
-				case 3:
-					if testCase.IsFailed() {
-						printOut(fmt.Sprintf("pre-tasks (%d):", len(beforeScriptsLog)), beforeScriptsLog, 2)
-						printOut("case task:", mainScriptLog)
-						printOut("debug:", debugScriptLog)
-						printOut(fmt.Sprintf("post-tasks (%d):", len(afterScriptsLog)), afterScriptsLog, 2)
+				for k, v := range details {
+					if k == len(details)-1 {
+						fmt.Fprintf(w, "%s└── %s\n", indent, v)
+					} else {
+						fmt.Fprintf(w, "%s├── %s\n", indent, v)
 					}
-				case 4:
-					printOut(fmt.Sprintf("pre-tasks (%d):", len(beforeScriptsLog)), beforeScriptsLog, 2)
-					printOut("case task:", mainScriptLog)
-					printOut("debug:", debugScriptLog)
-					printOut(fmt.Sprintf("post-tasks (%d):", len(afterScriptsLog)), afterScriptsLog, 2)
 				}
 			}
-			return
+			if n < len(d)-1 {
+				fmt.Fprintln(w)
+			}
+		}
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		if withTasks {
+			fmt.Fprintln(tw, "FILE\tINDEX\tCONTROL\tCASE\tITEM\tTAGS\tWEIGHT\tBEFORE\tAFTER\tENV")
+		} else {
+			fmt.Fprintln(tw, "FILE\tINDEX\tCONTROL\tCASE\tITEM\tTAGS\tWEIGHT")
+		}
+		for _, item := range items {
+			loopItem := item.Item
+			if item.Loop != "" {
+				loopItem = "(loop.command)"
+			}
+			row := []string{item.File, item.Index, item.Control, item.Case, loopItem, strings.Join(item.Tags, ","), strconv.Itoa(item.Weight)}
+			if withTasks {
+				row = append(row, strings.Join(item.Before, ","), strings.Join(item.After, ","), strings.Join(sortedEnv(item.Env), " "))
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
 		}
+		tw.Flush()
+		fmt.Fprintf(w, "%d cases in %d suites\n", len(items), len(d))
 	}
 }
 
-func (c *suitConfig) execTask(item int) {
-	testCase := &c.Cases[item]
+func showHistory(fileName string, last int, top int) {
+	entries, err := history.Load(fileName, last)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	if testCase.Skip {
-		testCase.skipReason = "'skip=true' setting"
+	if len(entries) == 0 {
+		log.Printf("There are no runs in the history file: %s", fileName)
 		return
 	}
 
-	if testCase.Script == "" {
-		testCase.skipReason = "empty 'script' setting"
-		testCase.Skip = true
-		return
+	stats := history.Analyze(entries, top)
+	ms := func(v int) string {
+		return (time.Duration(v) * time.Millisecond).String()
 	}
 
-	taskStartTime := time.Now()
+	log.Printf("History of %d runs, %s - %s, file: %s", stats.Runs, entries[0].Time.Format("2006-01-02 15:04"), entries[len(entries)-1].Time.Format("2006-01-02 15:04"), fileName)
+	log.Println()
 
-	for _, name := range testCase.Before {
-		c.Cases[c.getIdByName(name)].RunBash(c.Env)
+	log.Println("Rating trends:")
+	for _, trend := range stats.Trends {
+		ratings := []string{}
+		for _, r := range trend.Ratings {
+			ratings = append(ratings, fmt.Sprintf("%.2f%%", r))
+		}
+		log.Printf("  [ %s ], file: %s", trend.Name, trend.File)
+		log.Printf("    %s, mean duration %s", strings.Join(ratings, " → "), ms(trend.MeanDurationMilliSeconds))
 	}
+	log.Println()
 
-	testCase.RunBash(c.Env)
-
-	for _, name := range testCase.After {
-		c.Cases[c.getIdByName(name)].RunBash(c.Env)
+	log.Println("Flaky cases:")
+	if len(stats.Flaky) == 0 {
+		log.Println("  none")
 	}
+	for _, cs := range stats.Flaky {
+		marks := ""
+		for _, state := range cs.States {
+			switch state {
+			case scoring.StatusPassed:
+				marks += "✓"
+			case scoring.StatusFailed:
+				marks += "✗"
+			default:
+				marks += "-"
+			}
+		}
+		print(fmt.Sprintf("  %s, %d changes in %d runs: %s", cs.ID, cs.Flips, cs.Runs, marks))
+	}
+	log.Println()
 
-	testCase.durationString, testCase.durationMilliSeconds = duration(taskStartTime, time.Now())
+	log.Println("Slowest cases:")
+	for _, cs := range stats.Slowest {
+		log.Printf("  %s, mean %s, max %s in %d runs", cs.ID, ms(cs.MeanDurationMilliSeconds), ms(cs.MaxDurationMilliSeconds), cs.Runs)
+	}
 }
 
-func (t *suitConfig) getConf(config string, taskFilter ...string) *suitConfig {
-	yamlFile, err := os.ReadFile(config)
-
-	if err != nil {
-		log.Fatal(err)
-	}
+// serverRun is a run requested via HTTP API, runs are executed one by one
+type serverRun struct {
+	ID        int             `json:"id"`
+	Status    string          `json:"status"`
+	File      string          `json:"file,omitempty"`
+	Scheduled bool            `json:"scheduled,omitempty"`
+	Filter    string          `json:"filter,omitempty"`
+	Tag       string          `json:"tag,omitempty"`
+	Control   string          `json:"control,omitempty"`
+	Created   time.Time       `json:"created"`
+	Started   *time.Time      `json:"started,omitempty"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+	Summary   *report.Summary `json:"summary,omitempty"`
+	Error     string          `json:"error,omitempty"`
+
+	output  runOutput
+	reports map[string][]byte
+}
 
-	err = yaml.Unmarshal(yamlFile, t)
-	if err != nil {
-		log.Fatalf("Cannot recognize configuration structure in file: %s", config)
-	}
+// runOutput keeps console output of the run, without colors
+type runOutput struct {
+	mu   sync.Mutex
+	data []byte
+}
 
-	// var envs map[string]string
-	wdir, _ := os.Getwd()
-	if workdir != "" {
-		wdir = workdir
-	}
+func (o *runOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.data = append(o.data, regexp.MustCompile(`\033[^m]*m`).ReplaceAll(p, []byte(""))...)
+	return len(p), nil
+}
 
-	if len(t.EnvFiles) > 0 {
-		for _, sourceFile := range t.EnvFiles {
-			file, err := os.Open(sourceFile)
-			if err != nil {
-				// t.errors = append(t.errors, err)
-				continue
-			}
+func (o *runOutput) readFrom(offset int) []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]byte{}, o.data[offset:]...)
+}
 
-			// Use bufio.Scanner to read the file line by line
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := scanner.Text()
+// serverEvent is a case status change between two scheduled runs of the suite
+type serverEvent struct {
+	Time   time.Time `json:"time"`
+	RunID  int       `json:"runId"`
+	Suite  string    `json:"suite"`
+	File   string    `json:"file"`
+	ID     string    `json:"id"`
+	Case   string    `json:"case"`
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+}
 
-				parts := strings.SplitN(line, "=", 2)
-				if len(parts) != 2 {
-					continue
-				}
+// maxServerEvents limits the number of events kept by the server
+const maxServerEvents = 1000
 
-				key, value := parts[0], parts[1]
+type server struct {
+	mu        sync.Mutex
+	runs      []*serverRun
+	suites    []checkup.Item
+	schedules map[string]string
+	events    []serverEvent
+	queue     chan *serverRun
 
-				if t.Env == nil {
-					t.Env = make(map[string]string)
-				}
-				t.Env[key] = value
-			}
+	// previous results by the run file, accessed by the worker only
+	previous map[string]*report.Run
+}
 
-			// Close the file after reading
-			if err := file.Close(); err != nil {
-				// fmt.Println("Error closing file:", err)
-				continue
-			}
+func serve(address string) {
+	if *localConfig == "" && *remoteConfig == "" {
+		log.Fatal("serve command requires -c or -C option")
+	}
 
-			// Check for errors during line scanning
-			// if err := scanner.Err(); err != nil {
-			// 	fmt.Println("Error reading file:", err)
-			// }
-		}
+	if *resultsDir == "" {
+		*resultsDir = filepath.Join(filepath.Dir(history.DefaultFile()), "results")
 	}
 
-	a := &suitConfig{
-		Name:        (*t).Name,
-		CustomIndex: (*t).CustomIndex,
-		Cases:       []ScenarioItem{},
+	s := &server{queue: make(chan *serverRun, 100), previous: map[string]*report.Run{}}
+	if err := s.refreshSuites(); err != nil {
+		log.Fatal(err)
 	}
 
-	for i := 0; i < len((*t).Cases); i++ {
-		if (*t).Env != nil {
-			if (*t).Cases[i].Env == nil {
-				(*t).Cases[i].Env = make(map[string]string)
-			}
-			for key, value := range (*t).Env {
-				if _, exists := (*t).Cases[i].Env[key]; !exists {
-					(*t).Cases[i].Env[key] = value
-				}
-			}
+	schedules := map[string]schedule.Schedule{}
+	for file, spec := range s.schedules {
+		sched, err := schedule.Parse(spec)
+		if err != nil {
+			log.Fatalf("suite %s: %v", file, err)
 		}
+		schedules[file] = sched
+	}
 
-		if (*t).Cases[i].Workdir != "" {
-			wdir = (*t).Cases[i].Workdir
-		} else {
-			(*t).Cases[i].Workdir = wdir
-			if wdir == "" {
-				wdir, _ = os.Getwd()
-			}
-		}
+	go s.worker()
+	for file, sched := range schedules {
+		go s.scheduler(file, s.schedules[file], sched)
+	}
 
-		if (*t).Cases[i].Case != "" {
-			if len(taskFilter) > 0 {
-				if strings.Contains((*t).Cases[i].Case, taskFilter[0]) {
-					(*t).Cases[i].canShow = true
-					(*t).Cases[i].canRun = true
-				}
-			} else {
-				(*t).Cases[i].canShow = true
-				(*t).Cases[i].canRun = true
-			}
-		}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /suites", s.handleSuites)
+	mux.HandleFunc("GET /runs", s.handleRuns)
+	mux.HandleFunc("POST /runs", s.handleNewRun)
+	mux.HandleFunc("GET /runs/{id}", s.handleRun)
+	mux.HandleFunc("GET /runs/{id}/output", s.handleRunOutput)
+	mux.HandleFunc("GET /runs/{id}/report", s.handleRunReport)
+	mux.HandleFunc("GET /events", s.handleEvents)
+
+	log.Printf("checkup %s is listening on %s, suites: %s%s", version, address, *localConfig, *remoteConfig)
+	log.Fatal(http.ListenAndServe(address, mux))
+}
 
-		if (*t).Cases[i].Name == "" {
-			(*t).Cases[i].canRun = true
-		}
+// refreshSuites loads the cases list without evaluating loop commands, it's called from the worker only
+func (s *server) refreshSuites() error {
+	opts := runnerOptions()
+	opts.Filter, opts.Tags, opts.Controls = "", "", ""
+	opts.SkipLoopCommand = true
 
-		if (*t).Cases[i].CanShow() {
-			if (*t).Cases[i].Weight == 0 {
-				(*t).Cases[i].Weight = 1
-			}
-		}
+	d, err := loadSuites(checkup.NewRunner(opts))
+	if err != nil {
+		return err
+	}
 
-		if *timeout > 0 {
-			(*t).Cases[i].Timeout = *timeout
-			(*t).Cases[i].Debug.Timeout = *timeout
+	items := []checkup.Item{}
+	schedules := map[string]string{}
+	for _, c := range d {
+		items = append(items, c.Items(true)...)
+		if c.Schedule != "" {
+			schedules[c.FileName] = c.Schedule
 		}
+	}
 
-		if len((*t).Cases[i].Loop.Items) > 0 || len((*t).Cases[i].Loop.Command) > 0 {
-			Items := []string{}
+	s.mu.Lock()
+	s.suites = items
+	if s.schedules == nil {
+		s.schedules = schedules
+	}
+	s.mu.Unlock()
+	return nil
+}
 
-			if len((*t).Cases[i].Loop.Items) > 0 {
-				Items = (*t).Cases[i].Loop.Items
-			}
+// scheduler queues runs of the suite file according to its 'schedule' setting, the spec is validated by serve
+func (s *server) scheduler(file string, spec string, sched schedule.Schedule) {
+	log.Printf("suite %s is scheduled: %s", file, spec)
 
-			if len((*t).Cases[i].Loop.Command) > 0 {
-				s := (*t).Cases[i]
-				s.Script = s.Loop.Command
-				stdout, _ := s.RunBash((*t).Env)
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("suite %s: schedule '%s' never fires", file, spec)
+			return
+		}
+		time.Sleep(time.Until(next))
+
+		s.mu.Lock()
+		run := &serverRun{
+			ID:        len(s.runs) + 1,
+			Status:    "queued",
+			File:      file,
+			Scheduled: true,
+			Created:   time.Now(),
+		}
+		s.runs = append(s.runs, run)
+		s.mu.Unlock()
 
-				for _, item := range strings.Split(string(stdout), "\n") {
-					if item != "" {
-						Items = append(Items, item)
-					}
-				}
-			}
+		s.queue <- run
+	}
+}
 
-			for _, item := range Items {
-				last := len(a.Cases)
-				a.Cases = append(a.Cases, t.Cases[i])
+// retainResults saves the results of the scheduled run and emits events for the cases which status changed
+func (s *server) retainResults(run *serverRun, r *checkup.Runner, d []*checkup.Suite, logger *log.Logger) {
+	for _, c := range d {
+		suiteRun := r.Report([]*checkup.Suite{c})
+		dir := filepath.Join(*resultsDir, regexp.MustCompile(`[^A-Za-z0-9._-]+`).ReplaceAllString(strings.TrimPrefix(c.FileName, "./"), "_"))
 
-				nameHasItemVar := regexp.MustCompile(`\$\{?item\}?`)
-				if len(nameHasItemVar.FindStringSubmatch((*t).Cases[i].Case)) > 0 {
-					(*a).Cases[last].Case = nameHasItemVar.ReplaceAllString((*t).Cases[i].Case, item)
-				} else {
-					(*a).Cases[last].Case = fmt.Sprintf("%s, item => \"%s\"", (*t).Cases[i].Case, item)
-				}
+		previous, found := report.LatestRetained(dir)
+		if err := report.SaveRetained(dir, suiteRun.Marshal(), time.Now(), *retain); err != nil {
+			logger.Println(err)
+		}
 
-				a.Cases[last].Env = make(map[string]string)
-				for k, v := range t.Cases[i].Env {
-					a.Cases[last].Env[k] = v
-				}
+		if !found {
+			continue
+		}
 
-				a.Cases[last].Env["item"] = item
+		comparison := report.Compare(previous, suiteRun)
+		for _, change := range append(comparison.NewlyFailing, comparison.NewlyPassing...) {
+			event := serverEvent{
+				Time:   time.Now(),
+				RunID:  run.ID,
+				Suite:  c.Name,
+				File:   c.FileName,
+				ID:     change.ID,
+				Case:   change.Name,
+				Before: change.Before,
+				After:  change.After,
 			}
+			logger.Printf("event: [ %s ] %s: %s -> %s", event.Suite, event.Case, event.Before, event.After)
 
-		} else {
-			a.Cases = append(a.Cases, t.Cases[i])
+			s.addEvent(event)
 		}
-
 	}
-	t = a
-	return t
 }
 
-func load(tmpFile *os.File, URL string) error {
-	resp, err := http.Get(URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// addEvent keeps the latest maxServerEvents events, the oldest ones are dropped
+func (s *server) addEvent(event serverEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	_, err = io.Copy(tmpFile, resp.Body)
-	return err
+	s.events = append(s.events, event)
+	if len(s.events) > maxServerEvents {
+		s.events = append([]serverEvent(nil), s.events[len(s.events)-maxServerEvents:]...)
+	}
 }
 
-func duration(start time.Time, finish time.Time) (string, int) {
-	result := finish.Sub(start).Truncate(time.Millisecond)
-	resultInMilliSeconds := int(result.Milliseconds())
-	return result.String(), resultInMilliSeconds
-}
+// fail marks the run as failed, e.g. when the suites can't be loaded anymore
+func (s *server) fail(run *serverRun, err error, logger *log.Logger) {
+	logger.Println(err)
 
-type reportFile struct {
-	fileName string
-	format   string
+	s.mu.Lock()
+	finished := time.Now()
+	run.Status, run.Finished = "failed", &finished
+	run.Error = err.Error()
+	s.mu.Unlock()
 }
 
-func (r *reportFile) parse(d string) {
-	config := strings.Split(d, "=")
-	if len(config) > 1 {
-		(*r).fileName = config[1]
-		(*r).format = config[0]
-	}
-}
+func (s *server) worker() {
+	for run := range s.queue {
+		s.mu.Lock()
+		started := time.Now()
+		run.Status, run.Started = "running", &started
+		s.mu.Unlock()
 
-var report reportFile
+		for _, w := range activeWaivers {
+			w.Matched = 0
+		}
 
-func jUnitReportSave(reportFile string, c suitConfig) {
-	if reportFile != "" {
+		// the run has its own logger, so that messages of concurrent handlers don't get into the run output
+		output := io.MultiWriter(consoleOutput(os.Stdout), &run.output)
+		logger := log.New(output, "", 0)
 
-		T := struct {
-			SuitName    string
-			TotalTests  int
-			FailedTests int
-			Tests       []ScenarioItem
-			TotalTime   string
-			TimeStamp   string
-			Verbosity   int
-		}{
-			SuitName:    c.Name,
-			TotalTests:  c.all,
-			FailedTests: c.failed,
-			Tests:       c.Cases,
-			TotalTime:   c.durationString,
-			TimeStamp:   time.Now().Format("2006-01-02T15:04:05"),
-			Verbosity:   0,
-		}
+		opts := runnerOptions()
+		opts.Filter, opts.Tags, opts.Controls = run.Filter, run.Tag, run.Control
+		opts.Output = output
+		runner := checkup.NewRunner(opts)
 
-		funcMap := template.FuncMap{
-			"Quote": func(m string) string {
-				return strconv.Quote(m)
-			},
+		if err := s.refreshSuites(); err != nil {
+			s.fail(run, err, logger)
+			continue
 		}
 
-		reportFile, err := os.Create(reportFile)
+		d, err := loadSuites(runner)
 		if err != nil {
-			log.Println(err)
-			return
+			s.fail(run, err, logger)
+			continue
 		}
-		defer reportFile.Close()
+		if run.File != "" {
+			selected := []*checkup.Suite{}
+			for _, c := range d {
+				if c.FileName == run.File {
+					selected = append(selected, c)
+				}
+			}
+			d = selected
+		}
+		runner.Run(d)
+		if run.Scheduled {
+			s.retainResults(run, runner, d, logger)
+		}
+
+		runData := runner.Report(d)
+		junit, _ := checkup.JUnit(d)
+		reports := map[string][]byte{
+			"json":       runData.Marshal(),
+			"junit":      junit,
+			"prometheus": prometheus.Format(runData, time.Now()),
+		}
+
+		sendNotifications(runner, d, s.previous[run.File], logger)
+		s.previous[run.File] = &runData
 
-		jut, _ := template.New("junit report").Funcs(funcMap).Parse(string(jUnit.JUnitTemplate))
-		jut.Execute(reportFile, T)
+		s.mu.Lock()
+		finished := time.Now()
+		run.Status, run.Finished = "finished", &finished
+		run.Summary = &runData.Summary
+		run.reports = reports
+		s.mu.Unlock()
 	}
 }
 
-func jsonReportSave(reportFile string, c suitConfig) {
-	type TestData struct {
-		Name     string `json:"name"`
-		Status   bool   `json:"status"`
-		Duration string `json:"duration"`
-		Stdout   string `json:"stdout"`
-	}
+func (s *server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	s.mu.Lock()
+	body, _ := json.MarshalIndent(data, "", "  ")
+	s.mu.Unlock()
 
-	type TestsSummary struct {
-		Success  int     `json:"success"`
-		Failed   int     `json:"failed"`
-		Rating   float64 `json:"rating"`
-		Duration string  `json:"duration"`
-	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+func (s *server) findRun(w http.ResponseWriter, r *http.Request) *serverRun {
+	id, err := strconv.Atoi(r.PathValue("id"))
 
-	type JsonStructure struct {
-		TestName string       `json:"testName"`
-		Tests    []TestData   `json:"tests"`
-		Summary  TestsSummary `json:"summary"`
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil && id > 0 && id <= len(s.runs) {
+		return s.runs[id-1]
 	}
 
-	var jsonReportData JsonStructure
-	jsonReportData.TestName = c.Name
-	jsonReportData.Tests = []TestData{}
+	http.Error(w, "run not found", http.StatusNotFound)
+	return nil
+}
 
-	if c.getScenarioCount() > 0 {
-		for _, id := range c.getScenarioIds() {
-			if c.Cases[id].CanShow() {
-				t := TestData{
-					Name:     c.Cases[id].Case,
-					Status:   c.Cases[id].IsSuccessful(),
-					Duration: c.Cases[id].durationString,
-				}
+func (s *server) handleSuites(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, &s.suites)
+}
 
-				if (verbosity > 1 && c.Cases[id].IsFailed()) || (verbosity > 2) {
-					t.Stdout = c.Cases[id].stdout
-				}
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, &s.events)
+}
 
-				jsonReportData.Tests = append(jsonReportData.Tests, t)
-			}
-		}
-	}
+func (s *server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, &s.runs)
+}
 
-	jsonReportData.Summary = TestsSummary{
-		Success:  c.successfull,
-		Failed:   c.failed,
-		Rating:   c.score,
-		Duration: c.durationString,
+func (s *server) handleNewRun(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	run := &serverRun{
+		ID:      len(s.runs) + 1,
+		Status:  "queued",
+		File:    query.Get("file"),
+		Filter:  query.Get("filter"),
+		Tag:     query.Get("tag"),
+		Control: query.Get("control"),
+		Created: time.Now(),
+	}
+	s.runs = append(s.runs, run)
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- run:
+		s.writeJSON(w, http.StatusAccepted, run)
+	default:
+		s.mu.Lock()
+		run.Status = "rejected"
+		s.mu.Unlock()
+		http.Error(w, "too many queued runs", http.StatusServiceUnavailable)
 	}
-
-	reportJson, _ := json.MarshalIndent(jsonReportData, "", "  ")
-	os.WriteFile(reportFile, reportJson, 0644)
 }
 
-var (
-	localConfig               = flag.String("c", "", "Local tests case file path (Required unless -C cpecified)")
-	remoteConfig              = flag.String("C", "", "Remote tests case file url (Required unless -c specified)")
-	filter                    = flag.String("f", "", "Run tests by name regexp match")
-	wdir                      = flag.String("w", "", "Set working Dir")
-	reportFlag                = flag.String("o", "", "JSON or JUnit report file")
-	timeout                   = flag.Int("t", 0, "Timeout of the task execution")
-	generateSampleTesCaseFile = flag.Bool("g", false, "")
-)
-
-var verbosity int = 0
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if run := s.findRun(w, r); run != nil {
+		s.writeJSON(w, http.StatusOK, run)
+	}
+}
 
-func listFiles(path string) []string {
-	var result []string
+// handleRunOutput streams console output of the run until it's finished
+func (s *server) handleRunOutput(w http.ResponseWriter, r *http.Request) {
+	run := s.findRun(w, r)
+	if run == nil {
+		return
+	}
 
-	var walkDir func(dirPath string)
-	walkDir = func(dirPath string) {
-		entries, err := os.ReadDir(dirPath)
-		if err != nil {
-			log.Fatal(err)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+
+	offset := 0
+	for {
+		s.mu.Lock()
+		status := run.Status
+		s.mu.Unlock()
+
+		data := run.output.readFrom(offset)
+		if len(data) > 0 {
+			offset += len(data)
+			w.Write(data)
+			if flusher != nil {
+				flusher.Flush()
+			}
 		}
 
-		for _, entry := range entries {
-			fullPath := filepath.Join(dirPath, entry.Name())
-			if entry.IsDir() {
-				walkDir(fullPath)
-			} else if filepath.Ext(fullPath) == ".yaml" || filepath.Ext(fullPath) == ".yml" {
-				result = append(result, fullPath)
+		if status == "finished" || status == "failed" || status == "rejected" {
+			if len(run.output.readFrom(offset)) == 0 {
+				return
 			}
+			continue
 		}
-	}
 
-	f, err := os.Stat(path)
-	if err != nil {
-		log.Fatal(err)
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
 	}
+}
 
-	if f.IsDir() {
-		walkDir(path)
-	} else {
-		result = append(result, path)
+func (s *server) handleRunReport(w http.ResponseWriter, r *http.Request) {
+	run := s.findRun(w, r)
+	if run == nil {
+		return
 	}
 
-	if len(result) == 0 {
-		log.Fatalf("There are no yaml or yml files found in the path: %s", path)
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
 	}
 
-	return result
-}
+	contentTypes := map[string]string{
+		"json":       "application/json",
+		"junit":      "application/xml",
+		"prometheus": "text/plain; version=0.0.4",
+	}
 
-func main() {
-	log.SetFlags(0)
-	log.SetOutput(os.Stdout)
+	s.mu.Lock()
+	data, status, runError := run.reports[format], run.Status, run.Error
+	s.mu.Unlock()
 
-	// Modified Args slice
-	args := os.Args[:1] // keep the program name
-	verbosity = 0
-	for _, arg := range os.Args[1:] {
-		if arg != "--version" {
-			matches := regexp.MustCompile(`^-v(v+)?=?(\d+)?$|^--verbosity=(\d+)$`).FindStringSubmatch(arg)
-			if len(matches) > 0 {
-				if matches[0] == "-v" && matches[1] == "" { // Handle -v, means the same as -v=1
-					verbosity = 1
-				} else if matches[1] != "" { // Handle -vv, -vvv (matches multiple 'v' after the first)
-					verbosity = len(matches[1]) + 1 // +1 because the first 'v' wasn't counted in matches[1]
-				} else if matches[2] != "" { // Handle -v=2, -v=3, etc.
-					verbosity, _ = strconv.Atoi(matches[2])
-				} else if matches[3] != "" { // Handle --verbosity=2, --verbosity=3, etc.
-					verbosity, _ = strconv.Atoi(matches[3])
-				}
+	switch {
+	case status == "failed":
+		http.Error(w, "run failed: "+runError, http.StatusConflict)
+	case status != "finished":
+		http.Error(w, "run is not finished yet", http.StatusConflict)
+	case data == nil:
+		http.Error(w, "unsupported report format, expected one of: json, junit, prometheus", http.StatusBadRequest)
+	default:
+		w.Header().Set("Content-Type", contentTypes[format])
+		w.Write(data)
+	}
+}
 
-			} else {
-				args = append(args, arg) // include other args to be parsed by flag package
+func handleReports(r *checkup.Runner, d []*checkup.Suite) {
+	run := r.Report(d)
+	run.Baseline = baselineComparison
+
+	for _, output := range outputs {
+		switch output.format {
+		case "junit":
+			jUnitReportSave(output.fileName, d)
+		case "json":
+			jsonReportSave(output.fileName, run)
+		case "prometheus":
+			if err := prometheus.Save(output.fileName, run, time.Now()); err != nil {
+				log.Println(err)
 			}
-		} else {
-			log.Println(version)
-			os.Exit(0)
 		}
 	}
+}
 
-	os.Args = args
-
-	flag.Usage = helper.CustomUsage
-	flag.Parse()
-
-	if *generateSampleTesCaseFile {
-		log.Printf(helper.SampleTestFile)
-		os.Exit(0)
+// previousRun is the baseline report, or the latest run from the history file,
+// used to tell whether the results changed
+func previousRun() *report.Run {
+	if *baselineFile != "" {
+		if baseline, err := report.Load(*baselineFile); err == nil {
+			return &baseline
+		}
 	}
 
-	workdir = *wdir
-	report.parse(*reportFlag)
-
-	d := []suitConfig{}
-	if *localConfig != "" {
-		for _, file := range listFiles(*localConfig) {
-			if len(file) > 0 {
-				d = append(d, *(&suitConfig{}).getConf(file, *filter))
-				cwdir, _ := os.Getwd()
-				d[len(d)-1].FileName = strings.Replace(file, cwdir, ".", 1)
-			}
-		}
+	entries, err := history.Load(*historyFile, 1)
+	if err != nil || len(entries) == 0 {
+		return nil
 	}
 
-	if *remoteConfig != "" {
-		tmpDir, err := os.MkdirTemp("/var/tmp", ".")
-		if err != nil {
-			log.Fatalf("Failed to create a temporary directory: %v", err)
-		}
-		defer os.RemoveAll(tmpDir)
+	run := entries[0].Run()
+	return &run
+}
 
-		tmpFile, err := os.CreateTemp(tmpDir, "tmp.*")
-		if err != nil {
-			log.Fatalf("Failed to create a temporary file: %v", err)
-		}
-		defer tmpFile.Close()
+// changedSince compares the run with the same suites of the previous one,
+// a run without previous results is considered as changed
+func changedSince(previous *report.Run, run report.Run) bool {
+	if previous == nil {
+		return true
+	}
 
-		if matched, _ := regexp.MatchString("^http(s)?://", *remoteConfig); matched {
-			load(tmpFile, *remoteConfig)
-			*localConfig = tmpFile.Name()
+	before := report.Run{Summary: previous.Summary}
+	for _, suite := range previous.Suites {
+		for _, current := range run.Suites {
+			if suite.File == current.File && suite.TestName == current.TestName {
+				before.Suites = append(before.Suites, suite)
+			}
 		}
-
-		d = append(d, *(&suitConfig{}).getConf(*localConfig, *filter))
+	}
+	if len(before.Suites) == 0 {
+		return true
 	}
 
-	if len(d) > 0 {
-		for _, c := range d {
-			handleScenarios(&c)
-			handleReports(&c)
+	comparison := report.Compare(before, run)
+	return comparison.Changed()
+}
+
+func hasSuiteNotifications(d []*checkup.Suite) bool {
+	for _, c := range d {
+		if len(c.Notify) > 0 {
+			return true
 		}
-	} else {
-		flag.Usage()
 	}
+	return false
 }
 
-func handleScenarios(c *suitConfig) {
-	c.printHeader()
-	if c.getScenarioCount() > 0 {
-		max := 30
-		for i, id := range c.getScenarioIds() {
-			taskTitle := fmt.Sprintf("   %d/%d  %s", i, c.getScenarioCount(), c.Cases[id].Case)
-			if max < len(taskTitle) {
-				max = len(taskTitle)
-			}
+// sendNotifications posts the run summary to --notify sinks, and every suite summary to its own 'notify' sinks, errors go to the logger
+func sendNotifications(r *checkup.Runner, d []*checkup.Suite, previous *report.Run, logger *log.Logger) {
+	send := func(sinks []notify.Sink, run report.Run) {
+		if len(sinks) == 0 {
+			return
 		}
 
-		log.Println(strings.Repeat("-", max+7))
-		j := 0
-		for _, id := range c.getScenarioIds() {
-			c.execTask(id)
-			if c.Cases[id].CanShow() {
-				c.printTestStatus(id, j+1)
-				j++
-			} else if c.Cases[id].Case == "" {
-				c.printTestStatus(id, j+1)
+		payload := notify.NewPayload(run, changedSince(previous, run), time.Now())
+		for _, sink := range sinks {
+			if !sink.Matches(payload) {
+				continue
+			}
+			if err := sink.Send(payload); err != nil {
+				logger.Println(err)
 			}
 		}
-
-		log.Println(strings.Repeat("-", max+7))
 	}
-	c.signOff()
-	c.printSummary()
-}
 
-func handleReports(c *suitConfig) {
-	switch report.format {
-	case "junit":
-		jUnitReportSave(report.fileName, *c)
-	case "json":
-		jsonReportSave(report.fileName, *c)
+	send(notifySinks, r.Report(d))
+	for _, c := range d {
+		send(c.Notify, r.Report([]*checkup.Suite{c}))
 	}
 }
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/checkup"
+	"github.com/sbeliakou/check-up/modules/report"
+)
+
+const listSuite = `
+name: Web server
+cases:
+- name: prepare
+  script: |
+    true
+- case: Port is open
+  control_id: "1.1"
+  tags: [network]
+  before: [prepare]
+  env:
+    PORT: "80"
+    API_TOKEN: s3cr3t
+  script: |
+    true
+- case: Config is valid
+  control_id: "1.2"
+  weight: 3
+  skip: true
+  script: |
+    true
+- case: Service $item is running
+  loop:
+    command: echo nginx
+  script: |
+    true
+`
+
+func listedSuites(t *testing.T) []*checkup.Suite {
+	t.Helper()
+	suite, err := checkup.LoadSuite(strings.NewReader(listSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite.FileName = "web.yaml"
+
+	runner := checkup.NewRunner(checkup.Options{SkipLoopCommand: true, Output: &bytes.Buffer{}})
+	return []*checkup.Suite{runner.Expand(suite)}
+}
+
+func TestListScenarios(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		withTasks bool
+		want      []string
+	}{
+		{
+			name:   "table",
+			format: "table",
+			want: []string{
+				"FILE      INDEX  CONTROL  CASE                      ITEM            TAGS     WEIGHT\n",
+				"web.yaml  1/3    1.1      Port is open                              network  1\n",
+				"web.yaml  2/3    1.2      Config is valid                                    3\n",
+				"web.yaml  3/3             Service $item is running  (loop.command)           1\n",
+				"3 cases in 1 suites",
+			},
+		},
+		{
+			name:      "table with tasks",
+			format:    "table",
+			withTasks: true,
+			want:      []string{"WEIGHT  BEFORE   AFTER  ENV\n", "1       prepare         API_TOKEN=*** PORT=80\n"},
+		},
+		{
+			name:      "tree",
+			format:    "tree",
+			withTasks: true,
+			want: []string{
+				"web.yaml [ Web server ]",
+				"├── 1/3 Port is open (weight: 1, control: 1.1, tags: network)",
+				"│   ├── before: prepare",
+				"│   ├── env: API_TOKEN=***",
+				"│   └── env: PORT=80",
+				"├── 2/3 Config is valid (weight: 3, control: 1.2, skipped)",
+				"└── 3/3 Service $item is running (weight: 1, loop command not evaluated)",
+				"    └── loop.command: echo nginx",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			listScenarios(&b, listedSuites(t), tt.format, tt.withTasks)
+			for _, line := range tt.want {
+				if !strings.Contains(b.String(), line) {
+					t.Errorf("output doesn't contain %q:\n%s", line, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestListScenariosJSON(t *testing.T) {
+	var b bytes.Buffer
+	listScenarios(&b, listedSuites(t), "json", false)
+
+	var items []checkup.Item
+	if err := json.Unmarshal(b.Bytes(), &items); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, b.String())
+	}
+
+	want := []checkup.Item{
+		{File: "web.yaml", Suite: "Web server", Index: "1/3", Control: "1.1", Case: "Port is open", Tags: []string{"network"}, Weight: 1},
+		{File: "web.yaml", Suite: "Web server", Index: "2/3", Control: "1.2", Case: "Config is valid", Weight: 3, Skip: true},
+		{File: "web.yaml", Suite: "Web server", Index: "3/3", Case: "Service $item is running", Loop: "echo nginx", Weight: 1},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %+v, want %+v", items, want)
+	}
+	for i := range want {
+		got, _ := json.Marshal(items[i])
+		expected, _ := json.Marshal(want[i])
+		if string(got) != string(expected) {
+			t.Errorf("item %d = %s, want %s", i, got, expected)
+		}
+	}
+}
+
+func TestListFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"table", false},
+		{"json", false},
+		{"tree", false},
+		{"yaml", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			f := listFormat("table")
+			err := f.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && string(f) != tt.value {
+				t.Errorf("format = %s, want %s", f, tt.value)
+			}
+		})
+	}
+}
+
+func TestLoadSuitesErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("cases: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing path", filepath.Join(dir, "missing.yaml")},
+		{"invalid suite", invalid},
+		{"no suites", t.TempDir()},
+	}
+
+	defer func(config string) { *localConfig = config }(*localConfig)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*localConfig = tt.path
+			if _, err := loadSuites(checkup.NewRunner(checkup.Options{Output: &bytes.Buffer{}})); err == nil {
+				t.Errorf("loadSuites(%s) error = nil, want an error", tt.path)
+			}
+		})
+	}
+}
+
+func TestServerFailedRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "web.yaml")
+	if err := os.WriteFile(file, []byte(listSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(config string) { *localConfig = config }(*localConfig)
+	*localConfig = file
+
+	s := &server{queue: make(chan *serverRun, 1), previous: map[string]*report.Run{}}
+	if err := s.refreshSuites(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the suite is deleted after the server started, the run fails but the worker keeps going
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	run := &serverRun{ID: 1, Status: "queued"}
+	s.runs = append(s.runs, run)
+	s.queue <- run
+	close(s.queue)
+	s.worker()
+
+	if run.Status != "failed" || !strings.Contains(run.Error, "web.yaml") {
+		t.Fatalf("run status = %s, error = %q, want failed with the error", run.Status, run.Error)
+	}
+	if !strings.Contains(string(run.output.readFrom(0)), run.Error) {
+		t.Errorf("run output = %q, want the error", run.output.readFrom(0))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /runs/{id}/output", s.handleRunOutput)
+	mux.HandleFunc("GET /runs/{id}/report", s.handleRunReport)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/runs/1/output", http.StatusOK},
+		{"/runs/1/report", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), "web.yaml") {
+				t.Errorf("GET %s = %d %q, want %d with the error", tt.path, rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServerEventsLimit(t *testing.T) {
+	s := &server{}
+	for i := 0; i < maxServerEvents+10; i++ {
+		s.addEvent(serverEvent{Time: time.Unix(int64(i), 0), ID: fmt.Sprint(i)})
+	}
+
+	if len(s.events) != maxServerEvents {
+		t.Fatalf("events = %d, want %d", len(s.events), maxServerEvents)
+	}
+	if first, last := s.events[0].ID, s.events[len(s.events)-1].ID; first != "10" || last != fmt.Sprint(maxServerEvents+9) {
+		t.Errorf("events = %s..%s, want the latest ones", first, last)
+	}
+}
+
+func TestLoadRemoteSuite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid.yaml" {
+			w.Write([]byte("cases: [\n"))
+			return
+		}
+		w.Write([]byte(listSuite))
+	}))
+	defer server.Close()
+
+	defer func(config string) { *remoteConfig = config }(*remoteConfig)
+
+	*remoteConfig = server.URL + "/web.yaml"
+	runner := checkup.NewRunner(checkup.Options{SkipLoopCommand: true, Output: &bytes.Buffer{}})
+	d, err := loadSuites(runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d) != 1 || d[0].FileName != *remoteConfig {
+		t.Fatalf("suites = %d, file = %s, want the url", len(d), d[0].FileName)
+	}
+	if id := runner.Report(d).Suites[0].Tests[0].ID; id != *remoteConfig+"::1.1" {
+		t.Errorf("case id = %s, want %s::1.1", id, *remoteConfig)
+	}
+
+	*remoteConfig = server.URL + "/invalid.yaml"
+	if _, err := loadSuites(runner); err == nil || !strings.HasPrefix(err.Error(), *remoteConfig+":") {
+		t.Errorf("loadSuites() error = %v, want the error of %s", err, *remoteConfig)
+	}
+}
@@ -1,11 +1,16 @@
 package bash
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 )
 
@@ -124,37 +129,147 @@ fi
 `
 
 func RunBashScript(command string, workdir string, timeout int, env []string) ([]byte, error) {
+	return RunBashScriptStream(command, workdir, timeout, env, nil)
+}
+
+// lineWriter passes every complete line of the output to the callback
+type lineWriter struct {
+	clean   func(string) string
+	pending []byte
+	onLine  func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(w.clean(string(w.pending[:i])))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.pending) > 0 {
+		w.onLine(w.clean(string(w.pending)))
+		w.pending = nil
+	}
+}
+
+// RunBashScriptStream runs the script the same way as RunBashScript, and when onLine
+// is set, it's called with every line of stdout and stderr as soon as it's printed
+func RunBashScriptStream(command string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error) {
+	return RunScript("/bin/bash", command, workdir, timeout, env, onLine)
+}
+
+// extensions of the script files for interpreters which require them
+var extensions = map[string]string{
+	"pwsh":       ".ps1",
+	"powershell": ".ps1",
+}
+
+// IsBashCompatible tells whether the interpreter gets check-up helper functions: run, assert_*, skip, fail
+func IsBashCompatible(interpreter string) bool {
+	fields := strings.Fields(interpreter)
+	return len(fields) > 0 && filepath.Base(fields[0]) == "bash"
+}
+
+// RunScript runs the script with the interpreter command, e.g. "python3" or "pwsh -NoProfile",
+// passing the script file as its last argument
+func RunScript(interpreter string, command string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error) {
+	stdout, _, err := runScript(interpreter, command, workdir, timeout, env, onLine, false)
+	return stdout, err
+}
+
+// RunScriptStdout runs the script the same way as RunScript, and returns its stdout without stderr
+// along with the whole output, e.g. for parsing JSON the script prints
+func RunScriptStdout(interpreter string, command string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, []byte, error) {
+	return runScript(interpreter, command, workdir, timeout, env, onLine, true)
+}
+
+// lockedWriter serializes the writes of stdout and stderr copying goroutines
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func runScript(interpreter string, command string, workdir string, timeout int, env []string, onLine func(string), separate bool) ([]byte, []byte, error) {
 	var stdout []byte = []byte("")
 	var err error = nil
 
 	if command != "" {
+		args := strings.Fields(interpreter)
+		if len(args) == 0 {
+			return stdout, stdout, fmt.Errorf("interpreter is not defined")
+		}
+
 		tmpDir, _ := os.MkdirTemp("/var/tmp", "._")
 		defer os.RemoveAll(tmpDir)
 
-		tmpFile, _ := os.CreateTemp(tmpDir, "tmp.*")
+		tmpFile, _ := os.CreateTemp(tmpDir, "tmp.*"+extensions[filepath.Base(args[0])])
 
-		T := struct {
-			Script string
-		}{
-			Script: command,
-		}
+		if IsBashCompatible(interpreter) {
+			T := struct {
+				Script string
+			}{
+				Script: command,
+			}
 
-		tmpl, _ := template.New("bash-script").Parse(string(bashScript))
-		tmpl.Execute(tmpFile, T)
+			tmpl, _ := template.New("bash-script").Parse(string(bashScript))
+			tmpl.Execute(tmpFile, T)
+		} else {
+			tmpFile.WriteString(command)
+		}
+		tmpFile.Close()
 
-		script := exec.Command("timeout", strconv.Itoa(timeout), "/bin/bash", tmpFile.Name())
+		args = append(append([]string{strconv.Itoa(timeout)}, args...), tmpFile.Name())
+		script := exec.Command("timeout", args...)
 		script.Dir = workdir
 		script.Env = env
 
-		stdout, err = script.CombinedOutput()
+		// bash error prefixes are removed, other interpreters get the script file named just 'script'
+		re, _ := regexp.Compile(fmt.Sprintf("%s: line [\\d]+: ", regexp.QuoteMeta(tmpFile.Name())))
+		clean := func(s string) string {
+			return strings.ReplaceAll(re.ReplaceAllString(s, ""), tmpFile.Name(), "script")
+		}
+
+		var buf, stdoutOnly bytes.Buffer
+		var output io.Writer = &buf
+		var lines *lineWriter
+		if onLine != nil {
+			lines = &lineWriter{clean: clean, onLine: onLine}
+			output = io.MultiWriter(&buf, lines)
+		}
+
+		// the same writer makes stdout and stderr share the pipe, so the output keeps their order
+		if separate {
+			output = &lockedWriter{w: output}
+			script.Stdout = io.MultiWriter(output, &stdoutOnly)
+		} else {
+			script.Stdout = output
+		}
+		script.Stderr = output
+
+		err = script.Run()
+		if lines != nil {
+			lines.flush()
+		}
 
-		re, _ := regexp.Compile(fmt.Sprintf("%s: line [\\d]+: ", tmpFile.Name()))
-		stdout = []byte(re.ReplaceAllString(string(stdout), ""))
+		stdout = []byte(clean(buf.String()))
 
-		return stdout, err
+		return stdout, []byte(clean(stdoutOnly.String())), err
 	}
 
-	return []byte(""), nil
+	return []byte(""), []byte(""), nil
 }
 
 func ExplainExitCode(code int) string {
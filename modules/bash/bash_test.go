@@ -0,0 +1,51 @@
+package bash
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunBashScriptStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		wantLines []string
+		wantErr   bool
+	}{
+		{
+			name:      "stdout and stderr",
+			script:    "echo one\necho two >&2\nprintf three",
+			wantLines: []string{"one", "two", "three"},
+		},
+		{
+			name:      "failed script",
+			script:    "echo failing\nexit 3",
+			wantLines: []string{"failing"},
+			wantErr:   true,
+		},
+		{
+			name:   "no output",
+			script: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			stdout, err := RunBashScriptStream(tt.script, t.TempDir(), 10, os.Environ(), func(line string) {
+				lines = append(lines, line)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunBashScriptStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %q, want %q", lines, tt.wantLines)
+			}
+			if got := strings.Join(tt.wantLines, "\n"); strings.TrimSpace(string(stdout)) != got {
+				t.Errorf("output = %q, want %q", stdout, got)
+			}
+		})
+	}
+}
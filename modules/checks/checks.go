@@ -0,0 +1,171 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Checks are native alternatives to the case script, implemented in Go without spawning bash,
+// a case can define several of them, it passes when all of them pass
+type Checks struct {
+	File        *File        `yaml:"file"`
+	FileContent *FileContent `yaml:"file_content"`
+	Port        *Port        `yaml:"port"`
+	HTTP        *HTTP        `yaml:"http"`
+	User        *User        `yaml:"user"`
+	Service     *Service     `yaml:"service"`
+	Package     *Package     `yaml:"package"`
+
+	Sysctl       *Sysctl       `yaml:"sysctl"`
+	KernelModule *KernelModule `yaml:"kernel_module"`
+	Mount        *Mount        `yaml:"mount"`
+}
+
+// check is a single native check, lookup resolves $VAR and ${VAR} references in its settings,
+// relative paths are relative to workdir, ctx is cancelled when the case timeout expires
+type check interface {
+	validate() error
+	run(ctx context.Context, lookup func(string) string, workdir string) (string, error)
+}
+
+func (c Checks) list() []check {
+	result := []check{}
+	if c.File != nil {
+		result = append(result, c.File)
+	}
+	if c.FileContent != nil {
+		result = append(result, c.FileContent)
+	}
+	if c.Port != nil {
+		result = append(result, c.Port)
+	}
+	if c.HTTP != nil {
+		result = append(result, c.HTTP)
+	}
+	if c.User != nil {
+		result = append(result, c.User)
+	}
+	if c.Service != nil {
+		result = append(result, c.Service)
+	}
+	if c.Package != nil {
+		result = append(result, c.Package)
+	}
+	if c.Sysctl != nil {
+		result = append(result, c.Sysctl)
+	}
+	if c.KernelModule != nil {
+		result = append(result, c.KernelModule)
+	}
+	if c.Mount != nil {
+		result = append(result, c.Mount)
+	}
+	return result
+}
+
+// Defined tells whether the case has any native checks
+func (c Checks) Defined() bool {
+	return len(c.list()) > 0
+}
+
+// Validate checks the required settings of the defined checks
+func (c Checks) Validate() error {
+	for _, v := range c.list() {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs the checks one by one, the output has a line per check: what's found, or why it failed;
+// relative file paths are resolved against workdir as the scripts' ones are, the current directory if it's empty;
+// the checks are stopped when the timeout in seconds expires, as the scripts are, 0 means no timeout
+func (c Checks) Run(lookup func(string) string, workdir string, timeout int) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	lines, failures := []string{}, []string{}
+	for _, v := range c.list() {
+		line, err := runCheck(ctx, v, lookup, workdir)
+		if ctx.Err() != nil {
+			message := fmt.Sprintf("timed out after %d seconds", timeout)
+			lines = append(lines, message)
+			failures = append(failures, message)
+			return strings.Join(lines, "\n"), &Failure{Message: strings.Join(failures, "\n"), timedOut: true}
+		}
+		if err != nil {
+			failures = append(failures, err.Error())
+			lines = append(lines, err.Error())
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	output := strings.Join(lines, "\n")
+	if len(failures) > 0 {
+		return output, &Failure{Message: strings.Join(failures, "\n")}
+	}
+	return output, nil
+}
+
+// runCheck returns as soon as the context is done, the checks reading local files can't be interrupted
+func runCheck(ctx context.Context, v check, lookup func(string) string, workdir string) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		line, err := v.run(ctx, lookup, workdir)
+		done <- result{line, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Failure is the result of the failed check, it exits with 1 as a failed script would, or with 124 on timeout
+type Failure struct {
+	Message string
+
+	timedOut bool
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+func (f *Failure) ExitCode() int {
+	if f.timedOut {
+		return 124
+	}
+	return 1
+}
+
+// expand resolves variable references in the setting value
+func expand(value string, lookup func(string) string) string {
+	return os.Expand(value, lookup)
+}
+
+// expandPath resolves variable references in the path, relative paths are joined to workdir
+func expandPath(value string, lookup func(string) string, workdir string) string {
+	path := expand(value, lookup)
+	if path != "" && workdir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(workdir, path)
+	}
+	return path
+}
@@ -0,0 +1,209 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func noVars(string) string {
+	return ""
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		checks  Checks
+		wantErr bool
+	}{
+		{"file", Checks{File: &File{Path: "/etc/passwd", Type: "file", Mode: "0644"}}, false},
+		{"file without path", Checks{File: &File{}}, true},
+		{"file type", Checks{File: &File{Path: "/etc", Type: "directory"}}, true},
+		{"file mode", Checks{File: &File{Path: "/etc/passwd", Mode: "rw-r--r--"}}, true},
+		{"port", Checks{Port: &Port{Port: "22", State: "closed"}}, false},
+		{"port without port", Checks{Port: &Port{}}, true},
+		{"port state", Checks{Port: &Port{Port: "22", State: "listening"}}, true},
+		{"http", Checks{HTTP: &HTTP{URL: "http://localhost", BodyMatches: "ok|OK"}}, false},
+		{"http without url", Checks{HTTP: &HTTP{}}, true},
+		{"http body_matches", Checks{HTTP: &HTTP{URL: "http://localhost", BodyMatches: "("}}, true},
+		{"any of several", Checks{File: &File{Path: "/etc"}, Port: &Port{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.checks.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(name string) string {
+		return map[string]string{"DIR": dir}[name]
+	}
+
+	tests := []struct {
+		name    string
+		file    File
+		wantErr bool
+	}{
+		{"exists", File{Path: "$DIR/config"}, false},
+		{"type and mode", File{Path: "$DIR/config", Type: "file", Mode: "0600"}, false},
+		{"wrong mode", File{Path: "$DIR/config", Mode: "0644"}, true},
+		{"wrong type", File{Path: "$DIR/config", Type: "dir"}, true},
+		{"directory", File{Path: "${DIR}", Type: "dir"}, false},
+		{"missing", File{Path: "$DIR/missing"}, true},
+		{"expected absent", File{Path: "$DIR/missing", Exists: boolPtr(false)}, false},
+		{"unexpected", File{Path: "$DIR/config", Exists: boolPtr(false)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Checks{File: &tt.file}.Run(lookup, "", 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v, output: %s", err, tt.wantErr, output)
+			}
+		})
+	}
+}
+
+func TestRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("listen 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		checks  Checks
+		workdir string
+		wantErr bool
+	}{
+		{"file in workdir", Checks{File: &File{Path: "app.conf"}}, dir, false},
+		{"file content in workdir", Checks{FileContent: &FileContent{Path: "./app.conf", Contains: "listen"}}, dir, false},
+		{"glob in workdir", Checks{FileContent: &FileContent{Path: "*.conf", Contains: "8080"}}, dir, false},
+		{"absolute path", Checks{File: &File{Path: filepath.Join(dir, "app.conf")}}, t.TempDir(), false},
+		{"other workdir", Checks{File: &File{Path: "app.conf"}}, t.TempDir(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.checks.Run(noVars, tt.workdir, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v, output: %s", err, tt.wantErr, output)
+			}
+		})
+	}
+}
+
+func TestPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := fmt.Sprint(closedListener.Addr().(*net.TCPAddr).Port)
+	closedListener.Close()
+	defer listener.Close()
+
+	tests := []struct {
+		name    string
+		port    Port
+		wantErr bool
+	}{
+		{"open", Port{Host: "127.0.0.1", Port: open}, false},
+		{"expected closed", Port{Host: "127.0.0.1", Port: open, State: "closed"}, true},
+		{"closed", Port{Host: "127.0.0.1", Port: closed, State: "closed"}, false},
+		{"expected open", Port{Host: "127.0.0.1", Port: closed}, true},
+		{"not a number", Port{Host: "127.0.0.1", Port: "ssh"}, true},
+		{"out of range", Port{Host: "127.0.0.1", Port: "65536"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (Checks{Port: &tt.port}).Run(noVars, "", 0); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "status: %s", r.Header.Get("X-Status"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		http    HTTP
+		wantErr bool
+	}{
+		{"ok", HTTP{URL: server.URL}, false},
+		{"body", HTTP{URL: server.URL, Headers: map[string]string{"X-Status": "green"}, BodyMatches: "status: green"}, false},
+		{"body mismatch", HTTP{URL: server.URL, BodyMatches: "status: green"}, true},
+		{"expected status", HTTP{URL: server.URL + "/missing", Status: http.StatusNotFound}, false},
+		{"unexpected status", HTTP{URL: server.URL + "/missing"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (Checks{HTTP: &tt.http}).Run(noVars, "", 0); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	started := time.Now()
+	output, err := Checks{HTTP: &HTTP{URL: server.URL}}.Run(noVars, "", 1)
+
+	var failure *Failure
+	if !errors.As(err, &failure) || failure.ExitCode() != 124 {
+		t.Fatalf("Run() error = %v, want a timeout failure", err)
+	}
+	if !strings.Contains(output, "timed out after 1 seconds") {
+		t.Errorf("Run() output = %q, want the timeout message", output)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("Run() took %v, want it stopped at the timeout", elapsed)
+	}
+}
@@ -0,0 +1,281 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FileContent checks lines of the config file, following include directives when 'include' is set,
+// commented out lines are ignored
+type FileContent struct {
+	Path        string `yaml:"path"`
+	Contains    string `yaml:"contains"`
+	NotContains string `yaml:"not_contains"`
+	Matches     string `yaml:"matches"`
+	LineEquals  string `yaml:"line_equals"`
+
+	// key/value settings, e.g. 'PermitRootLogin no' or 'net.ipv4.ip_forward = 0'
+	Key          string `yaml:"key"`
+	Value        string `yaml:"value"`
+	ValueMatches string `yaml:"value_matches"`
+	Separator    string `yaml:"separator"`
+	Occurrence   string `yaml:"occurrence"`
+
+	Comment    string `yaml:"comment"`
+	Include    string `yaml:"include"`
+	IgnoreCase bool   `yaml:"ignore_case"`
+}
+
+// maxIncludeDepth stops include loops
+const maxIncludeDepth = 16
+
+// configLine is a line of the config file or one of its includes
+type configLine struct {
+	file   string
+	number int
+	text   string
+}
+
+// location is 'line N' in the checked file, or 'file:N' in the included one
+func (l configLine) location(path string) string {
+	if l.file == path {
+		return fmt.Sprintf("line %d", l.number)
+	}
+	return fmt.Sprintf("%s:%d", l.file, l.number)
+}
+
+func (c *FileContent) validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("file_content check: 'path' is required")
+	}
+	if c.Contains == "" && c.NotContains == "" && c.Matches == "" && c.LineEquals == "" && c.Key == "" {
+		return fmt.Errorf("file_content check of %s: one of 'contains', 'not_contains', 'matches', 'line_equals' or 'key' is required", c.Path)
+	}
+	if (c.Value != "" || c.ValueMatches != "") && c.Key == "" {
+		return fmt.Errorf("file_content check of %s: 'value' and 'value_matches' require 'key'", c.Path)
+	}
+	if _, err := regexp.Compile(c.Matches); err != nil {
+		return fmt.Errorf("file_content check of %s: matches: %v", c.Path, err)
+	}
+	if _, err := regexp.Compile(c.ValueMatches); err != nil {
+		return fmt.Errorf("file_content check of %s: value_matches: %v", c.Path, err)
+	}
+	switch c.Occurrence {
+	case "", "first", "last":
+	default:
+		return fmt.Errorf("file_content check of %s: unknown occurrence '%s', expected one of: first, last", c.Path, c.Occurrence)
+	}
+	return nil
+}
+
+func (c FileContent) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	path := expandPath(c.Path, lookup, workdir)
+	comment := c.Comment
+	if comment == "" {
+		comment = "#"
+	}
+
+	lines, err := c.read(path, comment, 0)
+	if err != nil {
+		return "", fmt.Errorf("file_content %s: %v", path, err)
+	}
+
+	equal := func(a, b string) bool {
+		if c.IgnoreCase {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+	has := func(text, substr string) bool {
+		if c.IgnoreCase {
+			return strings.Contains(strings.ToLower(text), strings.ToLower(substr))
+		}
+		return strings.Contains(text, substr)
+	}
+	// patterns are validated as they're written, but the expanded ones can still be invalid
+	compile := func(setting, re string) (*regexp.Regexp, error) {
+		if c.IgnoreCase {
+			re = "(?i)" + re
+		}
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			return nil, fmt.Errorf("file_content %s: %s: %v", path, setting, err)
+		}
+		return compiled, nil
+	}
+	find := func(match func(configLine) bool) *configLine {
+		for i := range lines {
+			if match(lines[i]) {
+				return &lines[i]
+			}
+		}
+		return nil
+	}
+
+	failures, found := []string{}, []string{}
+
+	if v := expand(c.Contains, lookup); v != "" {
+		if l := find(func(l configLine) bool { return has(l.text, v) }); l != nil {
+			found = append(found, fmt.Sprintf("%s: %s", l.location(path), strings.TrimSpace(l.text)))
+		} else {
+			failures = append(failures, fmt.Sprintf("no line contains '%s'", v))
+		}
+	}
+
+	if v := expand(c.NotContains, lookup); v != "" {
+		if l := find(func(l configLine) bool { return has(l.text, v) }); l != nil {
+			failures = append(failures, fmt.Sprintf("%s: unexpected '%s': %s", l.location(path), v, strings.TrimSpace(l.text)))
+		}
+	}
+
+	if v := expand(c.Matches, lookup); v != "" {
+		re, err := compile("matches", v)
+		if err != nil {
+			return "", err
+		}
+		if l := find(func(l configLine) bool { return re.MatchString(l.text) }); l != nil {
+			found = append(found, fmt.Sprintf("%s: %s", l.location(path), strings.TrimSpace(l.text)))
+		} else {
+			failures = append(failures, fmt.Sprintf("no line matches '%s'", v))
+		}
+	}
+
+	if v := expand(c.LineEquals, lookup); v != "" {
+		if l := find(func(l configLine) bool { return equal(strings.TrimSpace(l.text), strings.TrimSpace(v)) }); l != nil {
+			found = append(found, fmt.Sprintf("%s: %s", l.location(path), strings.TrimSpace(l.text)))
+		} else {
+			failures = append(failures, fmt.Sprintf("no line equals '%s'", v))
+		}
+	}
+
+	if key := expand(c.Key, lookup); key != "" {
+		var setting *configLine
+		var value string
+		for i := range lines {
+			k, v, ok := c.split(lines[i].text)
+			if ok && equal(k, key) {
+				setting, value = &lines[i], v
+				if c.Occurrence != "last" {
+					break
+				}
+			}
+		}
+
+		want := expand(c.Value, lookup)
+		var valueRe *regexp.Regexp
+		if c.ValueMatches != "" {
+			if valueRe, err = compile("value_matches", expand(c.ValueMatches, lookup)); err != nil {
+				return "", err
+			}
+		}
+
+		switch {
+		case setting == nil:
+			failures = append(failures, fmt.Sprintf("'%s' is not set", key))
+		case want != "" && !equal(value, want):
+			failures = append(failures, fmt.Sprintf("%s: expected %s '%s', got '%s'", setting.location(path), key, want, value))
+		case valueRe != nil && !valueRe.MatchString(value):
+			failures = append(failures, fmt.Sprintf("%s: expected %s to match '%s', got '%s'", setting.location(path), key, expand(c.ValueMatches, lookup), value))
+		default:
+			found = append(found, fmt.Sprintf("%s: %s", setting.location(path), strings.TrimSpace(setting.text)))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("file_content %s: %s", path, strings.Join(failures, "; "))
+	}
+
+	return fmt.Sprintf("file_content %s: %s", path, strings.Join(found, "; ")), nil
+}
+
+// split parses key/value line, the separator is whitespace unless it's set, quotes around the value are removed
+func (c FileContent) split(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+
+	var key, value string
+	if c.Separator == "" {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return "", "", false
+		}
+		key, value = fields[0], strings.TrimSpace(strings.TrimPrefix(text, fields[0]))
+	} else {
+		var ok bool
+		if key, value, ok = strings.Cut(text, c.Separator); !ok {
+			return "", "", false
+		}
+	}
+
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, true
+}
+
+// read loads lines of the files matching the path glob, skipping empty and commented out lines,
+// and replacing include directives with the lines of included files
+func (c FileContent) read(path string, comment string, depth int) ([]configLine, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("too many nested includes at %s", path)
+	}
+
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		if depth > 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("no such file")
+	}
+	sort.Strings(files)
+
+	result := []configLine{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for number := 1; scanner.Scan(); number++ {
+			text := scanner.Text()
+			trimmed := strings.TrimSpace(text)
+			if trimmed == "" || strings.HasPrefix(trimmed, comment) {
+				continue
+			}
+
+			if fields := strings.Fields(trimmed); c.Include != "" && len(fields) > 1 && strings.EqualFold(fields[0], c.Include) {
+				for _, include := range fields[1:] {
+					if !filepath.IsAbs(include) {
+						include = filepath.Join(filepath.Dir(file), include)
+					}
+					included, err := c.read(include, comment, depth+1)
+					if err != nil {
+						f.Close()
+						return nil, err
+					}
+					result = append(result, included...)
+				}
+				continue
+			}
+
+			result = append(result, configLine{file: file, number: number, text: text})
+		}
+		f.Close()
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		separator string
+		text      string
+		wantKey   string
+		wantValue string
+		wantOk    bool
+	}{
+		{"whitespace", "", "PermitRootLogin  no", "PermitRootLogin", "no", true},
+		{"value with spaces", "", "Banner /etc/issue net", "Banner", "/etc/issue net", true},
+		{"key only", "", "  UsePAM", "UsePAM", "", true},
+		{"separator", "=", "net.ipv4.ip_forward = 0", "net.ipv4.ip_forward", "0", true},
+		{"double quotes", "=", `PASS_MAX_DAYS="90"`, "PASS_MAX_DAYS", "90", true},
+		{"single quotes", "=", "umask='027'", "umask", "027", true},
+		{"unbalanced quotes", "=", `name="value`, "name", `"value`, true},
+		{"no separator", "=", "net.ipv4.ip_forward 0", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, value, ok := FileContent{Separator: tt.separator}.split(tt.text)
+			if key != tt.wantKey || value != tt.wantValue || ok != tt.wantOk {
+				t.Errorf("split() = %q, %q, %v, want %q, %q, %v", key, value, ok, tt.wantKey, tt.wantValue, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestFileContentValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content FileContent
+		wantErr bool
+	}{
+		{"contains", FileContent{Path: "/etc/ssh/sshd_config", Contains: "Protocol 2"}, false},
+		{"expanded pattern", FileContent{Path: "/etc/hosts", Matches: "^$ADDRESS\\s"}, false},
+		{"no path", FileContent{Contains: "a"}, true},
+		{"no condition", FileContent{Path: "/etc/hosts"}, true},
+		{"value without key", FileContent{Path: "/etc/hosts", Contains: "a", Value: "b"}, true},
+		{"invalid matches", FileContent{Path: "/etc/hosts", Matches: "[a"}, true},
+		{"invalid value_matches", FileContent{Path: "/etc/hosts", Key: "a", ValueMatches: "(b"}, true},
+		{"unknown occurrence", FileContent{Path: "/etc/hosts", Key: "a", Occurrence: "any"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.content.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sshd_config": `# PermitRootLogin yes
+Include sshd_config.d/*.conf
+PermitRootLogin no
+MaxAuthTries 4
+MaxAuthTries 6
+`,
+		"sshd_config.d/10-banner.conf": "Banner /etc/issue.net\n",
+		"sysctl.conf":                  "net.ipv4.ip_forward = 0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vars := map[string]string{"DIR": dir, "PATTERN": "[a", "TRIES": "4"}
+	lookup := func(name string) string {
+		return vars[name]
+	}
+	sshd := "$DIR/sshd_config"
+
+	tests := []struct {
+		name        string
+		content     FileContent
+		wantErr     bool
+		wantMessage string
+	}{
+		{"contains", FileContent{Path: sshd, Contains: "permitrootlogin no", IgnoreCase: true}, false, ""},
+		{"commented out", FileContent{Path: sshd, NotContains: "PermitRootLogin yes"}, false, ""},
+		{"not contains", FileContent{Path: sshd, NotContains: "MaxAuthTries"}, true, "unexpected 'MaxAuthTries'"},
+		{"line equals", FileContent{Path: sshd, LineEquals: "PermitRootLogin no"}, false, ""},
+		{"matches", FileContent{Path: sshd, Matches: "^MaxAuthTries [0-4]$"}, false, ""},
+		{"first occurrence", FileContent{Path: sshd, Key: "MaxAuthTries", Value: "$TRIES"}, false, ""},
+		{"last occurrence", FileContent{Path: sshd, Key: "MaxAuthTries", Value: "$TRIES", Occurrence: "last"}, true, "expected MaxAuthTries '4', got '6'"},
+		{"value matches", FileContent{Path: sshd, Key: "MaxAuthTries", ValueMatches: "^[0-4]$"}, false, ""},
+		{"not set", FileContent{Path: sshd, Key: "Banner"}, true, "'Banner' is not set"},
+		{"included", FileContent{Path: sshd, Key: "Banner", Include: "Include"}, false, ""},
+		{"separator", FileContent{Path: "$DIR/sysctl.conf", Key: "net.ipv4.ip_forward", Value: "0", Separator: "="}, false, ""},
+		{"missing file", FileContent{Path: "$DIR/missing", Contains: "a"}, true, "no such file"},
+		{"invalid expanded matches", FileContent{Path: sshd, Matches: "$PATTERN"}, true, "matches: error parsing regexp"},
+		{"invalid expanded value_matches", FileContent{Path: sshd, Key: "MaxAuthTries", ValueMatches: "${PATTERN}"}, true, "value_matches: error parsing regexp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Checks{FileContent: &tt.content}.Run(lookup, "", 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v, output: %s", err, tt.wantErr, output)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.wantMessage)
+			}
+		})
+	}
+}
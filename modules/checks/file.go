@@ -0,0 +1,124 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// File checks the file presence, type, permissions and ownership
+type File struct {
+	Path   string `yaml:"path"`
+	Type   string `yaml:"type"`
+	Exists *bool  `yaml:"exists"`
+	Mode   string `yaml:"mode"`
+	Owner  string `yaml:"owner"`
+	Group  string `yaml:"group"`
+}
+
+func (f *File) validate() error {
+	if f.Path == "" {
+		return fmt.Errorf("file check: 'path' is required")
+	}
+	switch f.Type {
+	case "", "file", "dir", "symlink":
+	default:
+		return fmt.Errorf("file check of %s: unknown type '%s', expected one of: file, dir, symlink", f.Path, f.Type)
+	}
+	if f.Mode != "" {
+		if _, err := strconv.ParseUint(f.Mode, 8, 32); err != nil {
+			return fmt.Errorf("file check of %s: mode '%s' should be octal, e.g. 0644", f.Path, f.Mode)
+		}
+	}
+	return nil
+}
+
+func (f File) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	path := expandPath(f.Path, lookup, workdir)
+
+	stat := os.Stat
+	if f.Type == "symlink" {
+		stat = os.Lstat
+	}
+
+	info, err := stat(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("file %s: %v", path, err)
+	}
+
+	if f.Exists != nil && !*f.Exists {
+		if exists {
+			return "", fmt.Errorf("file %s: expected to be absent, but it exists", path)
+		}
+		return fmt.Sprintf("file %s: absent", path), nil
+	}
+	if !exists {
+		return "", fmt.Errorf("file %s: doesn't exist", path)
+	}
+
+	sys := info.Sys().(*syscall.Stat_t)
+	actual := map[string]string{
+		"type":  fileType(info),
+		"mode":  fmt.Sprintf("%04o", sys.Mode&07777),
+		"owner": userName(sys.Uid),
+		"group": groupName(sys.Gid),
+	}
+
+	mismatches := []string{}
+	if f.Type != "" && f.Type != actual["type"] {
+		mismatches = append(mismatches, fmt.Sprintf("expected type %s, got %s", f.Type, actual["type"]))
+	}
+	if f.Mode != "" {
+		mode, _ := strconv.ParseUint(f.Mode, 8, 32)
+		if uint64(sys.Mode&07777) != mode {
+			mismatches = append(mismatches, fmt.Sprintf("expected mode %04o, got %s", mode, actual["mode"]))
+		}
+	}
+	if owner := expand(f.Owner, lookup); owner != "" && owner != actual["owner"] && owner != strconv.Itoa(int(sys.Uid)) {
+		mismatches = append(mismatches, fmt.Sprintf("expected owner %s, got %s", owner, actual["owner"]))
+	}
+	if group := expand(f.Group, lookup); group != "" && group != actual["group"] && group != strconv.Itoa(int(sys.Gid)) {
+		mismatches = append(mismatches, fmt.Sprintf("expected group %s, got %s", group, actual["group"]))
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("file %s: %s", path, strings.Join(mismatches, "; "))
+	}
+
+	return fmt.Sprintf("file %s: %s, mode %s, owner %s:%s", path, actual["type"], actual["mode"], actual["owner"], actual["group"]), nil
+}
+
+func fileType(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "symlink"
+	case info.IsDir():
+		return "dir"
+	case info.Mode().IsRegular():
+		return "file"
+	}
+	return "special file"
+}
+
+// userName is the name of the user by id, or the id itself when it's unknown
+func userName(uid uint32) string {
+	id := strconv.Itoa(int(uid))
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// groupName is the name of the group by id, or the id itself when it's unknown
+func groupName(gid uint32) string {
+	id := strconv.Itoa(int(gid))
+	if g, err := user.LookupGroupId(id); err == nil {
+		return g.Name
+	}
+	return id
+}
@@ -0,0 +1,272 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sysctl checks the kernel parameter value in /proc/sys
+type Sysctl struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+func (s *Sysctl) validate() error {
+	if s.Key == "" {
+		return fmt.Errorf("sysctl check: 'key' is required")
+	}
+	return nil
+}
+
+func (s Sysctl) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	key := expand(s.Key, lookup)
+
+	data, err := os.ReadFile(filepath.Join("/proc/sys", strings.ReplaceAll(key, ".", "/")))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("sysctl %s: unknown key", key)
+		}
+		return "", fmt.Errorf("sysctl %s: %v", key, err)
+	}
+
+	// multi-value parameters are separated by tabs, e.g. net.ipv4.tcp_rmem
+	actual := strings.Join(strings.Fields(string(data)), " ")
+	want := strings.Join(strings.Fields(expand(s.Value, lookup)), " ")
+
+	if s.Value != "" && actual != want {
+		return "", fmt.Errorf("sysctl %s: expected '%s', got '%s'", key, want, actual)
+	}
+	return fmt.Sprintf("sysctl %s = %s", key, actual), nil
+}
+
+// KernelModule checks whether the module is loaded and whether it's disabled in modprobe configs,
+// either with 'blacklist <name>' or with 'install <name> /bin/true' directive
+type KernelModule struct {
+	Name        string `yaml:"name"`
+	Loaded      *bool  `yaml:"loaded"`
+	Blacklisted *bool  `yaml:"blacklisted"`
+}
+
+// modprobeDirs are searched for blacklist and install directives
+var modprobeDirs = []string{"/etc/modprobe.d", "/run/modprobe.d", "/usr/local/lib/modprobe.d", "/lib/modprobe.d", "/usr/lib/modprobe.d"}
+
+func (k *KernelModule) validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("kernel_module check: 'name' is required")
+	}
+	if k.Loaded == nil && k.Blacklisted == nil {
+		return fmt.Errorf("kernel_module check of %s: 'loaded' or 'blacklisted' is required", k.Name)
+	}
+	return nil
+}
+
+func (k KernelModule) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	name := expand(k.Name, lookup)
+	// module names are listed with underscores, while both forms are accepted by modprobe
+	normalized := strings.ReplaceAll(name, "-", "_")
+
+	loaded, err := moduleLoaded(normalized)
+	if err != nil {
+		return "", fmt.Errorf("kernel_module %s: %v", name, err)
+	}
+	blacklistedAt := moduleBlacklisted(normalized)
+
+	actual := []string{"not loaded", "not blacklisted"}
+	if loaded {
+		actual[0] = "loaded"
+	}
+	if blacklistedAt != "" {
+		actual[1] = "blacklisted at " + blacklistedAt
+	}
+
+	mismatches := []string{}
+	if k.Loaded != nil && *k.Loaded != loaded {
+		mismatches = append(mismatches, fmt.Sprintf("expected loaded=%t, got %s", *k.Loaded, actual[0]))
+	}
+	if k.Blacklisted != nil && *k.Blacklisted != (blacklistedAt != "") {
+		mismatches = append(mismatches, fmt.Sprintf("expected blacklisted=%t, got %s", *k.Blacklisted, actual[1]))
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("kernel_module %s: %s", name, strings.Join(mismatches, "; "))
+	}
+	return fmt.Sprintf("kernel_module %s: %s", name, strings.Join(actual, ", ")), nil
+}
+
+func moduleLoaded(name string) (bool, error) {
+	f, err := os.Open("/proc/modules")
+	if os.IsNotExist(err) {
+		// the kernel is built without loadable modules support
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 && fields[0] == name {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// moduleBlacklisted returns file:line of the directive disabling the module, or empty string
+func moduleBlacklisted(name string) string {
+	for _, dir := range modprobeDirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				continue
+			}
+
+			scanner := bufio.NewScanner(f)
+			for number := 1; scanner.Scan(); number++ {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) < 2 || strings.ReplaceAll(fields[1], "-", "_") != name {
+					continue
+				}
+
+				disabled := fields[0] == "blacklist"
+				if fields[0] == "install" && len(fields) > 2 {
+					switch filepath.Base(fields[2]) {
+					case "true", "false":
+						disabled = true
+					}
+				}
+				if disabled {
+					f.Close()
+					return fmt.Sprintf("%s:%d", file, number)
+				}
+			}
+			f.Close()
+		}
+	}
+	return ""
+}
+
+// Mount checks that the path is a mount point with the file system type and options
+type Mount struct {
+	Path    string   `yaml:"path"`
+	Mounted *bool    `yaml:"mounted"`
+	Fstype  string   `yaml:"fstype"`
+	Options []string `yaml:"options"`
+}
+
+func (m *Mount) validate() error {
+	if m.Path == "" {
+		return fmt.Errorf("mount check: 'path' is required")
+	}
+	return nil
+}
+
+// mountPoint is an entry of /proc/self/mountinfo
+type mountPoint struct {
+	fstype  string
+	source  string
+	options []string
+}
+
+func (m Mount) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	path := filepath.Clean(expand(m.Path, lookup))
+
+	mount, err := findMount(path)
+	if err != nil {
+		return "", fmt.Errorf("mount %s: %v", path, err)
+	}
+
+	if m.Mounted != nil && !*m.Mounted {
+		if mount != nil {
+			return "", fmt.Errorf("mount %s: expected not to be mounted, got %s on %s", path, mount.fstype, mount.source)
+		}
+		return fmt.Sprintf("mount %s: not mounted", path), nil
+	}
+	if mount == nil {
+		return "", fmt.Errorf("mount %s: not a mount point", path)
+	}
+
+	mismatches := []string{}
+	if fstype := expand(m.Fstype, lookup); fstype != "" && fstype != mount.fstype {
+		mismatches = append(mismatches, fmt.Sprintf("expected fstype %s, got %s", fstype, mount.fstype))
+	}
+
+	missing := []string{}
+	for _, option := range m.Options {
+		if !contains(mount.options, expand(option, lookup)) {
+			missing = append(missing, expand(option, lookup))
+		}
+	}
+	if len(missing) > 0 {
+		mismatches = append(mismatches, fmt.Sprintf("missing options %s", strings.Join(missing, ",")))
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("mount %s: %s (actual: %s, %s)", path, strings.Join(mismatches, "; "), mount.fstype, strings.Join(mount.options, ","))
+	}
+	return fmt.Sprintf("mount %s: %s on %s (%s)", path, mount.fstype, mount.source, strings.Join(mount.options, ",")), nil
+}
+
+// findMount returns the top most mount on the path, mount and super block options are merged
+func findMount(path string) (*mountPoint, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result *mountPoint
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i, field := range fields {
+			if field == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 6 || len(fields) < separator+4 || unescapeMount(fields[4]) != path {
+			continue
+		}
+
+		options := strings.Split(fields[5], ",")
+		for _, option := range strings.Split(fields[separator+3], ",") {
+			if !contains(options, option) {
+				options = append(options, option)
+			}
+		}
+
+		result = &mountPoint{fstype: fields[separator+1], source: fields[separator+2], options: options}
+	}
+
+	return result, scanner.Err()
+}
+
+// unescapeMount decodes octal escapes of mountinfo paths, e.g. \040 for space
+func unescapeMount(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
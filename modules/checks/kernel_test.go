@@ -0,0 +1,91 @@
+package checks
+
+import "testing"
+
+func TestKernelValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		checks  Checks
+		wantErr bool
+	}{
+		{"sysctl", Checks{Sysctl: &Sysctl{Key: "net.ipv4.ip_forward", Value: "0"}}, false},
+		{"sysctl without key", Checks{Sysctl: &Sysctl{Value: "0"}}, true},
+		{"kernel_module", Checks{KernelModule: &KernelModule{Name: "cramfs", Loaded: boolPtr(false)}}, false},
+		{"kernel_module without name", Checks{KernelModule: &KernelModule{Loaded: boolPtr(false)}}, true},
+		{"kernel_module without condition", Checks{KernelModule: &KernelModule{Name: "cramfs"}}, true},
+		{"mount", Checks{Mount: &Mount{Path: "/tmp", Options: []string{"nodev"}}}, false},
+		{"mount without path", Checks{Mount: &Mount{Fstype: "tmpfs"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.checks.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnescapeMount(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mnt/data", "/mnt/data"},
+		{`/mnt/my\040disk`, "/mnt/my disk"},
+		{`/mnt/tab\011and\134slash`, "/mnt/tab\tand\\slash"},
+		{`/mnt/not\09`, `/mnt/not\09`},
+		{`/mnt/end\04`, `/mnt/end\04`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := unescapeMount(tt.path); got != tt.want {
+				t.Errorf("unescapeMount() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysctl(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysctl  Sysctl
+		wantErr bool
+	}{
+		{"any value", Sysctl{Key: "kernel.ostype"}, false},
+		{"value", Sysctl{Key: "kernel.ostype", Value: "Linux"}, false},
+		{"other value", Sysctl{Key: "kernel.ostype", Value: "Darwin"}, true},
+		{"unknown key", Sysctl{Key: "kernel.no_such_key"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (Checks{Sysctl: &tt.sysctl}).Run(noVars, "", 0); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMount(t *testing.T) {
+	tests := []struct {
+		name    string
+		mount   Mount
+		wantErr bool
+	}{
+		{"root", Mount{Path: "/"}, false},
+		{"root is mounted", Mount{Path: "/", Mounted: boolPtr(false)}, true},
+		{"not a mount point", Mount{Path: "/no/such/mount/point"}, true},
+		{"expected not mounted", Mount{Path: "/no/such/mount/point", Mounted: boolPtr(false)}, false},
+		{"missing option", Mount{Path: "/", Options: []string{"no-such-option"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (Checks{Mount: &tt.mount}).Run(noVars, "", 0); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port checks whether the TCP port is open or closed
+type Port struct {
+	Host    string `yaml:"host"`
+	Port    string `yaml:"port"`
+	State   string `yaml:"state"`
+	Timeout int    `yaml:"timeout"`
+}
+
+func (p *Port) validate() error {
+	if p.Port == "" {
+		return fmt.Errorf("port check: 'port' is required")
+	}
+	switch p.State {
+	case "", "open", "closed":
+	default:
+		return fmt.Errorf("port check of %s: unknown state '%s', expected one of: open, closed", p.Port, p.State)
+	}
+	return nil
+}
+
+func (p Port) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	host := expand(p.Host, lookup)
+	if host == "" {
+		host = "localhost"
+	}
+	state := p.State
+	if state == "" {
+		state = "open"
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 3
+	}
+
+	port, err := strconv.Atoi(expand(p.Port, lookup))
+	if err != nil || port <= 0 || port > 65535 {
+		return "", fmt.Errorf("port %s: should be a number in range 1-65535", expand(p.Port, lookup))
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	actual, reason := "open", ""
+	if err == nil {
+		conn.Close()
+	} else {
+		actual, reason = "closed", fmt.Sprintf(" (%v)", unwrapNetError(err))
+	}
+
+	if actual != state {
+		return "", fmt.Errorf("port %s: expected %s, got %s%s", address, state, actual, reason)
+	}
+	return fmt.Sprintf("port %s: %s%s", address, actual, reason), nil
+}
+
+// unwrapNetError drops 'dial tcp ...' prefix of the connection error
+func unwrapNetError(err error) error {
+	if e, ok := err.(*net.OpError); ok && e.Err != nil {
+		return e.Err
+	}
+	return err
+}
+
+// HTTP checks the response status and body of the URL
+type HTTP struct {
+	URL         string            `yaml:"url"`
+	Method      string            `yaml:"method"`
+	Headers     map[string]string `yaml:"headers"`
+	Status      int               `yaml:"status"`
+	BodyMatches string            `yaml:"body_matches"`
+	Insecure    bool              `yaml:"insecure"`
+	Timeout     int               `yaml:"timeout"`
+}
+
+func (h *HTTP) validate() error {
+	if h.URL == "" {
+		return fmt.Errorf("http check: 'url' is required")
+	}
+	if h.BodyMatches != "" {
+		if _, err := regexp.Compile(h.BodyMatches); err != nil {
+			return fmt.Errorf("http check of %s: body_matches: %v", h.URL, err)
+		}
+	}
+	return nil
+}
+
+func (h HTTP) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	url := expand(h.URL, lookup)
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	status := h.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 10
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), url, nil)
+	if err != nil {
+		return "", fmt.Errorf("http %s: %v", url, err)
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, expand(v, lookup))
+	}
+
+	client := &http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: h.Insecure},
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http %s %s: %v", req.Method, url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode != status {
+		return "", fmt.Errorf("http %s %s: expected status %d, got %s", req.Method, url, status, resp.Status)
+	}
+
+	if h.BodyMatches != "" {
+		if !regexp.MustCompile(h.BodyMatches).Match(body) {
+			return "", fmt.Errorf("http %s %s: body doesn't match '%s', got: %s", req.Method, url, h.BodyMatches, excerpt(string(body)))
+		}
+	}
+
+	return fmt.Sprintf("http %s %s: %s", req.Method, url, resp.Status), nil
+}
+
+// excerpt shortens the text for failure messages
+func excerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > 200 {
+		return text[:200] + "..."
+	}
+	return text
+}
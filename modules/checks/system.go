@@ -0,0 +1,277 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"sort"
+	"strings"
+)
+
+// User checks the user account, its groups, shell and home directory
+type User struct {
+	Name   string   `yaml:"name"`
+	Exists *bool    `yaml:"exists"`
+	Groups []string `yaml:"groups"`
+	Shell  string   `yaml:"shell"`
+	Home   string   `yaml:"home"`
+}
+
+func (u *User) validate() error {
+	if u.Name == "" {
+		return fmt.Errorf("user check: 'name' is required")
+	}
+	return nil
+}
+
+func (u User) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	name := expand(u.Name, lookup)
+
+	account, err := user.Lookup(name)
+	if u.Exists != nil && !*u.Exists {
+		if err == nil {
+			return "", fmt.Errorf("user %s: expected to be absent, but it exists with uid %s", name, account.Uid)
+		}
+		return fmt.Sprintf("user %s: absent", name), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("user %s: doesn't exist", name)
+	}
+
+	groups := []string{}
+	if ids, err := account.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := user.LookupGroupId(id); err == nil {
+				groups = append(groups, g.Name)
+			} else {
+				groups = append(groups, id)
+			}
+		}
+	}
+	sort.Strings(groups)
+	shell := loginShell(name)
+
+	mismatches := []string{}
+	missing := []string{}
+	for _, group := range u.Groups {
+		group = expand(group, lookup)
+		if !contains(groups, group) {
+			missing = append(missing, group)
+		}
+	}
+	if len(missing) > 0 {
+		mismatches = append(mismatches, fmt.Sprintf("not a member of %s (groups: %s)", strings.Join(missing, ", "), strings.Join(groups, ", ")))
+	}
+	if want := expand(u.Shell, lookup); want != "" && want != shell {
+		mismatches = append(mismatches, fmt.Sprintf("expected shell %s, got %s", want, shell))
+	}
+	if want := expand(u.Home, lookup); want != "" && want != account.HomeDir {
+		mismatches = append(mismatches, fmt.Sprintf("expected home %s, got %s", want, account.HomeDir))
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("user %s: %s", name, strings.Join(mismatches, "; "))
+	}
+
+	return fmt.Sprintf("user %s: uid %s, groups %s, shell %s, home %s", name, account.Uid, strings.Join(groups, ","), shell, account.HomeDir), nil
+}
+
+// loginShell reads the shell of the user from /etc/passwd, it's empty when the user isn't there
+func loginShell(name string) string {
+	f, err := os.Open("/etc/passwd")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) == 7 && fields[0] == name {
+			return fields[6]
+		}
+	}
+	return ""
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Service checks the systemd unit state
+type Service struct {
+	Name    string `yaml:"name"`
+	Active  *bool  `yaml:"active"`
+	Enabled *bool  `yaml:"enabled"`
+}
+
+func (s *Service) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("service check: 'name' is required")
+	}
+	return nil
+}
+
+func (s Service) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	name := expand(s.Name, lookup)
+
+	out, err := exec.CommandContext(ctx, "systemctl", "show", "--property=LoadState,ActiveState,SubState,UnitFileState", "--", name).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			err = fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("service %s: systemctl failed: %v", name, err)
+	}
+
+	state := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			state[k] = v
+		}
+	}
+
+	active := state["ActiveState"] == "active"
+	enabled := state["UnitFileState"] == "enabled" || state["UnitFileState"] == "static"
+
+	// the unit is expected to be active unless only 'enabled' is checked
+	checkActive := s.Active != nil || s.Enabled == nil
+	wantActive := s.Active == nil || *s.Active
+
+	if state["LoadState"] == "not-found" && ((checkActive && wantActive) || (s.Enabled != nil && *s.Enabled)) {
+		return "", fmt.Errorf("service %s: unit not found", name)
+	}
+
+	mismatches := []string{}
+	if checkActive && active != wantActive {
+		mismatches = append(mismatches, fmt.Sprintf("expected active=%t, got %s (%s)", wantActive, state["ActiveState"], state["SubState"]))
+	}
+	if s.Enabled != nil && enabled != *s.Enabled {
+		mismatches = append(mismatches, fmt.Sprintf("expected enabled=%t, got %s", *s.Enabled, state["UnitFileState"]))
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("service %s: %s", name, strings.Join(mismatches, "; "))
+	}
+
+	return fmt.Sprintf("service %s: %s (%s), %s", name, state["ActiveState"], state["SubState"], state["UnitFileState"]), nil
+}
+
+// Package checks whether the package is installed, and its version against glob pattern, e.g. "1.2.*"
+type Package struct {
+	Name      string `yaml:"name"`
+	Version   string `yaml:"version"`
+	Installed *bool  `yaml:"installed"`
+}
+
+func (p *Package) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("package check: 'name' is required")
+	}
+	if _, err := path.Match(p.Version, ""); err != nil {
+		return fmt.Errorf("package check of %s: version: %v", p.Name, err)
+	}
+	return nil
+}
+
+func (p Package) run(ctx context.Context, lookup func(string) string, workdir string) (string, error) {
+	name := expand(p.Name, lookup)
+
+	version, found, err := installedVersion(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("package %s: %v", name, err)
+	}
+
+	if p.Installed != nil && !*p.Installed {
+		if found {
+			return "", fmt.Errorf("package %s: expected not to be installed, got version %s", name, version)
+		}
+		return fmt.Sprintf("package %s: not installed", name), nil
+	}
+	if !found {
+		return "", fmt.Errorf("package %s: not installed", name)
+	}
+
+	if want := expand(p.Version, lookup); want != "" {
+		if matched, _ := path.Match(want, version); !matched && want != version {
+			return "", fmt.Errorf("package %s: expected version %s, got %s", name, want, version)
+		}
+	}
+
+	return fmt.Sprintf("package %s: version %s", name, version), nil
+}
+
+// installedVersion looks the package up in dpkg or apk database, or asks rpm
+func installedVersion(ctx context.Context, name string) (string, bool, error) {
+	switch {
+	case exists("/var/lib/dpkg/status"):
+		return databaseVersion("/var/lib/dpkg/status", "Package: ", "Version: ", name, func(fields map[string]string) bool {
+			return strings.HasSuffix(fields["Status: "], " installed")
+		})
+	case exists("/lib/apk/db/installed"):
+		return databaseVersion("/lib/apk/db/installed", "P:", "V:", name, nil)
+	}
+
+	if _, err := exec.LookPath("rpm"); err != nil {
+		return "", false, fmt.Errorf("no supported package manager found: dpkg, apk or rpm")
+	}
+	out, err := exec.CommandContext(ctx, "rpm", "-q", "--queryformat", "%{VERSION}-%{RELEASE}", name).Output()
+	if err != nil {
+		return "", false, nil
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
+
+// databaseVersion reads the package database of blank line separated records
+func databaseVersion(file string, nameKey string, versionKey string, name string, installed func(map[string]string) bool) (string, bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	fields := map[string]string{}
+	match := func() (string, bool) {
+		if fields[nameKey] == name && (installed == nil || installed(fields)) {
+			return fields[versionKey], true
+		}
+		return "", false
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if version, ok := match(); ok {
+				return version, true, nil
+			}
+			fields = map[string]string{}
+			continue
+		}
+		for _, key := range []string{nameKey, versionKey, "Status: "} {
+			if strings.HasPrefix(line, key) {
+				fields[key] = strings.TrimPrefix(line, key)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, err
+	}
+
+	version, ok := match()
+	return version, ok, nil
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
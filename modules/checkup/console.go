@@ -0,0 +1,696 @@
+package checkup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/bash"
+	"github.com/sbeliakou/check-up/modules/expect"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/snapshot"
+)
+
+func (c *Suite) printHeader() {
+	scenariosCount := c.getScenarioCount()
+	filename := ""
+	if c.FileName != "" {
+		filename = fmt.Sprintf(", file: %s", c.FileName)
+	}
+
+	switch {
+	case scenariosCount > 1:
+		c.runner.log.Printf("[ %s ], 1..%d tests%s\n", c.Name, scenariosCount, filename)
+	case scenariosCount == 1:
+		c.runner.log.Printf("[ %s ], 1 test%s\n", c.Name, filename)
+	case scenariosCount == 0:
+		c.runner.log.Printf("[ %s ], no tests to run%s\n", c.Name, filename)
+	}
+}
+
+func (c *Suite) printSummary() {
+	if c.all > 0 {
+		failed := fmt.Sprintf("%d tests failed", c.failed)
+		if c.failed > 0 {
+			failed = fmt.Sprintf("\033[31m%s\033[0m", failed)
+		}
+
+		skipped := fmt.Sprintf("%d tests skipped", c.skipped)
+		if c.skipped > 0 {
+			skipped = fmt.Sprintf("\033[36m%s\033[0m", skipped)
+		}
+
+		if c.waived > 0 {
+			skipped = fmt.Sprintf("%s, \033[33m%d tests waived\033[0m", skipped, c.waived)
+		}
+
+		if c.failed > 0 {
+			c.runner.print(fmt.Sprintf("%d (of %d) tests passed, %s, %s, rated as %.2f%%, spent %s", c.successfull, c.all, failed, skipped, c.score, c.durationString))
+		} else {
+			c.runner.print(fmt.Sprintf("\033[32m%d (of %d) tests passed, %s, %s, rated as %.2f%%, spent %s\033[0m", c.successfull, c.all, failed, skipped, c.score, c.durationString))
+		}
+
+		if len(c.summary.Categories) > 1 {
+			c.runner.printCategories(c.summary.Categories)
+		}
+
+		if c.runner.Remediate {
+			states := map[string]int{}
+			for _, id := range c.getScenarioIds() {
+				states[c.Cases[id].remediation.state]++
+			}
+			c.runner.print(fmt.Sprintf("remediation: %d fixed, %d still failing, %d not remediable, %d declined, %d dry run",
+				states[RemediationFixed], states[RemediationStillFailing], states[RemediationNotRemediable], states[RemediationDeclined], states[RemediationDryRun]))
+		}
+	}
+
+	c.runner.print("")
+}
+
+func (r *Runner) printWaiversWarnings() {
+	for _, w := range r.Waivers {
+		if w.Expired {
+			r.print(fmt.Sprintf("\033[33mwarning: waiver '%s' (owner: %s) expired on %s\033[0m", w, w.Owner, w.Expires))
+		}
+		if w.Matched == 0 {
+			r.print(fmt.Sprintf("\033[33mwarning: waiver '%s' (owner: %s) doesn't match any failed case\033[0m", w, w.Owner))
+		}
+	}
+}
+
+func (r *Runner) printCategories(categories []scoring.Category) {
+	for _, category := range categories {
+		color := "\033[32m"
+		if category.Failed > 0 {
+			color = "\033[31m"
+		}
+		r.print(fmt.Sprintf("  %s%s: %d (of %d) tests passed, rated as %.2f%%\033[0m", color, category.Name, category.Passed, category.All, category.Rating))
+	}
+}
+
+// printTotal shows the grand total of the run when there's more than one suite
+func (r *Runner) printTotal(d []*Suite) {
+	results := []scoring.Result{}
+	for _, c := range d {
+		results = append(results, c.scoringResults()...)
+	}
+
+	if len(d) < 2 {
+		return
+	}
+
+	total := scoring.Calculate(results)
+
+	color := "\033[32m"
+	if total.Failed > 0 {
+		color = "\033[31m"
+	}
+
+	waived := ""
+	if total.Waived > 0 {
+		waived = fmt.Sprintf(", %d tests waived", total.Waived)
+	}
+
+	r.print(fmt.Sprintf("%sTotal: %d (of %d) tests passed, %d tests failed, %d tests skipped%s in %d suites, rated as %.2f%%\033[0m", color, total.Passed, total.All, total.Failed, total.Skipped, waived, len(d), total.Rating))
+	r.printCategories(total.Categories)
+	r.print("")
+}
+
+type taskScriptDetails struct {
+	Name    string
+	Script  string
+	Stdout  string
+	Result  error
+	Timeout int
+	Env     []string
+	Errors  []error
+
+	// EnvPolicy is shown with the environment at the highest verbosity, the environment is the effective one then,
+	// unless the policy inherits all process variables
+	EnvPolicy string
+
+	Expectations []expect.Result
+	Snapshot     *snapshot.Result
+}
+
+func (r *Runner) printOut(b string, t []taskScriptDetails, indent ...int) {
+	indentStr := "     "
+	if len(t) > 0 {
+		r.log.Println(indentStr[2:] + b)
+	} else {
+		r.log.Println(indentStr[2:] + b + " not defined\n")
+		return
+	}
+
+	if len(indent) > 0 {
+		indentStr = indentStr + strings.Repeat(" ", indent[0])
+	}
+
+	for _, item := range t {
+		if item.Name != "" {
+			r.log.Printf(indentStr[2:]+"%s", item.Name)
+		}
+
+		if len(item.Script) > 0 {
+			r.log.Println(indentStr + "script: |\n  " + indentStr + regexp.MustCompile(`\n`).ReplaceAllString(item.Script, "\n  "+indentStr))
+		}
+
+		if len(item.Stdout) == 0 {
+			r.log.Println(indentStr + "stdout: \"\" (output is empty)")
+		} else {
+			r.log.Println(indentStr + "stdout: |\n  " + indentStr + regexp.MustCompile(`\n`).ReplaceAllString(item.Stdout, "\n  "+indentStr))
+		}
+
+		if item.Timeout != 0 {
+			r.log.Printf(indentStr+"timeout: %d sec", item.Timeout)
+		} else {
+			r.log.Printf(indentStr + "timeout: not defined")
+		}
+
+		exitCodeInt := exitCode(item.Result)
+
+		color := "\033[31m"
+		if exitCodeInt == 0 {
+			color = "\033[32m"
+		}
+		r.log.Printf(indentStr+"exit code: %d (%s%s\033[0m)", exitCodeInt, color, bash.ExplainExitCode(exitCodeInt))
+		if exitCodeInt == -1 && item.Result != nil {
+			r.log.Printf(indentStr+"error: \033[31m%v\033[0m", item.Result)
+		}
+
+		if len(item.Expectations) > 0 {
+			r.log.Println(indentStr + "expectations:")
+			for _, v := range item.Expectations {
+				if v.Passed {
+					r.log.Println(indentStr + "  \033[32m✓\033[0m " + v.String())
+				} else {
+					r.log.Println(indentStr + "  \033[31m✗\033[0m " + v.String())
+				}
+			}
+		}
+
+		if item.Snapshot != nil {
+			r.log.Printf(indentStr+"snapshot: %s (%s)", item.Snapshot.State, item.Snapshot.File)
+			for _, line := range strings.Split(item.Snapshot.Diff, "\n") {
+				switch {
+				case line == "":
+				case strings.HasPrefix(line, "@@"):
+					r.log.Println(indentStr + "  \033[36m" + line + "\033[0m")
+				case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+					r.log.Println(indentStr + "  \033[32m" + line + "\033[0m")
+				case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+					r.log.Println(indentStr + "  \033[31m" + line + "\033[0m")
+				default:
+					r.log.Println(indentStr + "  " + line)
+				}
+			}
+		}
+
+		if item.EnvPolicy != "" {
+			r.log.Println(indentStr + "env_policy: " + item.EnvPolicy)
+		}
+		if len(item.Env) > 0 {
+			r.log.Println(indentStr + "environment:")
+			for _, v := range item.Env {
+				r.log.Println(indentStr + "  " + v)
+			}
+		}
+
+		if len(item.Errors) > 0 {
+			r.log.Println(indentStr + "warnings:")
+			for _, v := range item.Errors {
+				r.log.Println(indentStr + "  " + v.Error())
+			}
+		}
+
+		r.log.Println()
+	}
+}
+
+func (r *Runner) printCompliance(testCase Case) {
+	if testCase.ControlID == "" && testCase.Rationale == "" && testCase.Audit == "" && testCase.Remediation == "" && len(testCase.References) == 0 {
+		return
+	}
+
+	indentStr := "     "
+	multiline := func(name string, text string) {
+		text = strings.TrimSpace(text)
+		if text != "" {
+			r.log.Println(indentStr + name + ": |\n  " + indentStr + regexp.MustCompile(`\n`).ReplaceAllString(text, "\n  "+indentStr))
+		}
+	}
+
+	r.log.Println(indentStr[2:] + "compliance:")
+	if testCase.ControlID != "" {
+		if testCase.Level != "" {
+			r.log.Printf(indentStr+"control: %s (level %s)", testCase.ControlID, testCase.Level)
+		} else {
+			r.log.Printf(indentStr+"control: %s", testCase.ControlID)
+		}
+	}
+	multiline("rationale", testCase.Rationale)
+	multiline("audit", testCase.Audit)
+	multiline("remediation", testCase.Remediation)
+	if len(testCase.References) > 0 {
+		r.log.Println(indentStr + "references:")
+		for _, v := range testCase.References {
+			r.log.Println(indentStr + "  - " + v)
+		}
+	}
+	r.log.Println()
+}
+
+func (c *Suite) printTestStatus(id int, asId ...int) {
+	testCase := c.Cases[id]
+
+	status, color := "✗", "\033[31m" // Assume failure
+
+	if testCase.IsSuccessful() {
+		status, color = "✓", "\033[32m"
+	}
+
+	if testCase.IsWaived() {
+		status, color = "~", "\033[33m"
+	}
+
+	if testCase.Skip {
+		status, color = "-", "\033[36m"
+	}
+
+	i := id
+	if len(asId) > 0 {
+		i = asId[0]
+	}
+
+	caseStatusMsg := ""
+	if c.CustomIndex != "" {
+		caseStatusMsg = fmt.Sprintf("%s %s", c.caseIndex(id, i), testCase.Case)
+	} else {
+		if testCase.Case != "" {
+			caseStatusMsg = fmt.Sprintf("%s  %s", c.caseIndex(id, i), testCase.Case)
+		} else {
+			caseStatusMsg = fmt.Sprintf("%s  %s", c.caseIndex(id, i), "Silent task, not scored")
+		}
+	}
+
+	if testCase.Skip {
+		caseStatusMsg = fmt.Sprintf("%s%s %s, skipping reason: %s \033[0m", color, status, caseStatusMsg, testCase.skipReason)
+	} else if testCase.IsWaived() {
+		caseStatusMsg = fmt.Sprintf("%s%s %s, %s, waived: %s\033[0m", color, status, caseStatusMsg, testCase.durationString, testCase.waiver.Justification)
+	} else if testCase.snapshotResult != nil && (testCase.snapshotResult.State == snapshot.Recorded || testCase.snapshotResult.State == snapshot.Updated) {
+		caseStatusMsg = fmt.Sprintf("%s%s %s, %s, snapshot %s\033[0m", color, status, caseStatusMsg, testCase.durationString, testCase.snapshotResult.State)
+	} else if testCase.remediation.state != "" {
+		caseStatusMsg = fmt.Sprintf("%s%s %s, %s, remediation: %s\033[0m", color, status, caseStatusMsg, testCase.durationString, testCase.remediation.state)
+	} else {
+		caseStatusMsg = fmt.Sprintf("%s%s %s, %s\033[0m", color, status, caseStatusMsg, testCase.durationString)
+	}
+
+	for _, j := range c.getScenarioIds() {
+		if j == id {
+			if testCase.CanShow() || (c.runner.Verbosity >= 3) {
+				c.runner.log.Print(caseStatusMsg)
+
+				if testCase.Skip {
+					return
+				}
+
+				mainScriptLog := []taskScriptDetails{
+					{
+						Script:  strings.TrimSpace(testCase.Script),
+						Stdout:  strings.TrimSpace(testCase.stdout),
+						Result:  testCase.result,
+						Timeout: testCase.Timeout,
+						Env:     c.runner.masker.Env(testCase.declaredEnv, testCase.Secrets),
+						Errors:  testCase.errors,
+
+						Expectations: testCase.expectations,
+						Snapshot:     testCase.snapshotResult,
+					},
+				}
+
+				// the effective environment is listed when the policy limits the process variables,
+				// the inherited ones aren't, they may hold secrets which names aren't recognized
+				if c.runner.Verbosity >= 4 {
+					mainScriptLog[0].EnvPolicy = testCase.EnvPolicy.String()
+					if testCase.EnvPolicy.limited() {
+						mainScriptLog[0].Env = c.runner.masker.Env(testCase.env, testCase.Secrets)
+					}
+				}
+
+				debugScriptLog := []taskScriptDetails{}
+				if len(strings.TrimSpace(testCase.Debug.Script)) > 0 {
+					debugScriptLog = []taskScriptDetails{
+						{
+							Script:  strings.TrimSpace(testCase.Debug.Script),
+							Stdout:  strings.TrimSpace(testCase.Debug.stdout),
+							Result:  testCase.Debug.result,
+							Timeout: testCase.Debug.Timeout,
+							Errors:  testCase.errors,
+						},
+					}
+				}
+
+				beforeScriptsLog := []taskScriptDetails{}
+				for i, name := range testCase.Before {
+					beforeScriptsLog = append(beforeScriptsLog, taskScriptDetails{
+						Name:    fmt.Sprintf("%d/%d: %s", i+1, len(testCase.Before), strings.TrimSpace(c.Cases[c.getIdByName(name)].Name)),
+						Script:  strings.TrimSpace(c.Cases[c.getIdByName(name)].Script),
+						Stdout:  strings.TrimSpace(c.Cases[c.getIdByName(name)].stdout),
+						Result:  c.Cases[c.getIdByName(name)].result,
+						Timeout: c.Cases[c.getIdByName(name)].Timeout,
+						Errors:  c.Cases[c.getIdByName(name)].errors,
+					})
+				}
+
+				afterScriptsLog := []taskScriptDetails{}
+				for i, name := range testCase.After {
+					afterScriptsLog = append(afterScriptsLog, taskScriptDetails{
+						Name:    fmt.Sprintf("%d/%d: %s", i+1, len(testCase.After), strings.TrimSpace(c.Cases[c.getIdByName(name)].Name)),
+						Script:  strings.TrimSpace(c.Cases[c.getIdByName(name)].Script),
+						Stdout:  strings.TrimSpace(c.Cases[c.getIdByName(name)].stdout),
+						Result:  c.Cases[c.getIdByName(name)].result,
+						Timeout: c.Cases[c.getIdByName(name)].Timeout,
+						Errors:  c.Cases[c.getIdByName(name)].errors,
+					})
+				}
+
+				if c.runner.Verbosity > 0 && testCase.IsFailed() {
+					c.runner.printCompliance(testCase)
+				}
+
+				switch {
+				case testCase.remediation.state == RemediationDryRun:
+					c.runner.log.Println("   remediation (dry run), would execute:")
+					c.runner.log.Println("     script: |\n       " + regexp.MustCompile(`\n`).ReplaceAllString(strings.TrimSpace(testCase.Remediate), "\n       "))
+					c.runner.log.Println()
+				case c.runner.Verbosity > 0 && (testCase.remediation.state == RemediationFixed || testCase.remediation.state == RemediationStillFailing):
+					c.runner.printOut("remediation:", []taskScriptDetails{
+						{
+							Script:  strings.TrimSpace(testCase.Remediate),
+							Stdout:  testCase.remediation.stdout,
+							Result:  testCase.remediation.result,
+							Timeout: testCase.Timeout,
+						},
+					})
+				}
+
+				switch c.runner.Verbosity {
+				case 1:
+					if testCase.IsFailed() {
+						c.runner.printOut("case task:", mainScriptLog)
+					}
+				case 2:
+					if testCase.IsFailed() {
+						c.runner.printOut("case task:", mainScriptLog)
+						c.runner.printOut("debug:", debugScriptLog)
+					}
+				case 3:
+					if testCase.IsFailed() {
+						c.runner.printOut(fmt.Sprintf("pre-tasks (%d):", len(beforeScriptsLog)), beforeScriptsLog, 2)
+						c.runner.printOut("case task:", mainScriptLog)
+						c.runner.printOut("debug:", debugScriptLog)
+						c.runner.printOut(fmt.Sprintf("post-tasks (%d):", len(afterScriptsLog)), afterScriptsLog, 2)
+					}
+				case 4:
+					c.runner.printOut(fmt.Sprintf("pre-tasks (%d):", len(beforeScriptsLog)), beforeScriptsLog, 2)
+					c.runner.printOut("case task:", mainScriptLog)
+					c.runner.printOut("debug:", debugScriptLog)
+					c.runner.printOut(fmt.Sprintf("post-tasks (%d):", len(afterScriptsLog)), afterScriptsLog, 2)
+				}
+			}
+			return
+		}
+	}
+}
+
+// Reporter renders the progress and the results of the run on the console
+type Reporter interface {
+	SuiteStarted(c *Suite)
+	CaseFinished(c *Suite, id int, index int)
+	SuiteFinished(c *Suite)
+	RunFinished(d []*Suite)
+}
+
+var reporters = map[string]func() Reporter{
+	"default":        func() Reporter { return &textReporter{} },
+	"dots":           func() Reporter { return &dotsReporter{} },
+	"json":           func() Reporter { return &jsonReporter{} },
+	"github-actions": func() Reporter { return &githubReporter{} },
+}
+
+// NewReporter makes the console reporter by its name: default, dots, json or github-actions
+func NewReporter(name string) (Reporter, error) {
+	if name == "" {
+		name = "default"
+	}
+	newReporter, ok := reporters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown reporter '%s', expected one of: default, dots, json, github-actions", name)
+	}
+	return newReporter(), nil
+}
+
+var ansiCodes = regexp.MustCompile(`\033\[[^m]*m`)
+
+// plainSymbols replace the status symbols along with the colors, e.g. for log files and CI consoles
+var plainSymbols = strings.NewReplacer("✓", "success", "✗", "FAILURE")
+
+// plainWriter strips color codes from the console output and replaces the status symbols with words
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, plainSymbols.Replace(string(ansiCodes.ReplaceAll(b, nil)))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// NoColors wraps the writer stripping color codes and status symbols, for Output of the runner
+func NoColors(w io.Writer) io.Writer {
+	return plainWriter{w}
+}
+
+// print writes the console line, status symbols are replaced along with the colors by NoColors writer
+func (r *Runner) print(msg string) {
+	r.log.Println(msg)
+}
+
+// textReporter is the classic check-up output
+type textReporter struct {
+	width int
+}
+
+func (r *textReporter) SuiteStarted(c *Suite) {
+	c.printHeader()
+
+	r.width = 30
+	for i, id := range c.getScenarioIds() {
+		taskTitle := fmt.Sprintf("   %d/%d  %s", i, c.getScenarioCount(), c.Cases[id].Case)
+		if r.width < len(taskTitle) {
+			r.width = len(taskTitle)
+		}
+	}
+
+	if c.getScenarioCount() > 0 {
+		c.runner.log.Println(strings.Repeat("-", r.width+7))
+	}
+}
+
+func (r *textReporter) CaseFinished(c *Suite, id int, index int) {
+	c.printTestStatus(id, index)
+}
+
+func (r *textReporter) SuiteFinished(c *Suite) {
+	if c.getScenarioCount() > 0 {
+		c.runner.log.Println(strings.Repeat("-", r.width+7))
+	}
+	c.printSummary()
+}
+
+func (r *textReporter) RunFinished(d []*Suite) {
+	if len(d) > 0 {
+		d[0].runner.printTotal(d)
+	}
+}
+
+// dotsReporter prints a single character per case, and details of failed cases after the suite
+type dotsReporter struct {
+	failed  []int
+	indexes map[int]int
+}
+
+func (r *dotsReporter) SuiteStarted(c *Suite) {
+	r.failed, r.indexes = []int{}, map[int]int{}
+	fmt.Fprintf(c.runner.Output, "[ %s ] ", c.Name)
+}
+
+func (r *dotsReporter) CaseFinished(c *Suite, id int, index int) {
+	testCase := &c.Cases[id]
+	if !testCase.CanShow() {
+		return
+	}
+
+	marks := map[string]string{
+		scoring.StatusPassed:  "\033[32m.\033[0m",
+		scoring.StatusFailed:  "\033[31mF\033[0m",
+		scoring.StatusSkipped: "\033[36mS\033[0m",
+		scoring.StatusWaived:  "\033[33mW\033[0m",
+	}
+	fmt.Fprint(c.runner.Output, marks[testCase.state()])
+
+	if testCase.IsFailed() {
+		r.failed = append(r.failed, id)
+		r.indexes[id] = index
+	}
+}
+
+func (r *dotsReporter) SuiteFinished(c *Suite) {
+	c.runner.log.Println()
+	for _, id := range r.failed {
+		c.printTestStatus(id, r.indexes[id])
+	}
+	c.printSummary()
+}
+
+func (r *dotsReporter) RunFinished(d []*Suite) {
+	if len(d) > 0 {
+		d[0].runner.printTotal(d)
+	}
+}
+
+// jsonReporter leaves the console to the event stream, which is printed to stdout
+type jsonReporter struct{}
+
+func (r *jsonReporter) SuiteStarted(c *Suite) {}
+
+func (r *jsonReporter) CaseFinished(c *Suite, id int, index int) {}
+
+func (r *jsonReporter) SuiteFinished(c *Suite) {}
+
+func (r *jsonReporter) RunFinished(d []*Suite) {}
+
+// githubReporter is the classic output with workflow commands annotating failed and waived cases
+type githubReporter struct {
+	textReporter
+}
+
+var githubEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+var githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func (r *githubReporter) CaseFinished(c *Suite, id int, index int) {
+	r.textReporter.CaseFinished(c, id, index)
+
+	testCase := &c.Cases[id]
+	if !testCase.CanShow() || !(testCase.IsFailed() || testCase.IsWaived()) {
+		return
+	}
+
+	file := strings.TrimPrefix(c.FileName, "./")
+	properties := fmt.Sprintf("file=%s", githubPropertyEscaper.Replace(file))
+	if line := caseLine(c.FileName, testCase.title); line > 0 {
+		properties = fmt.Sprintf("%s,line=%d", properties, line)
+	}
+	properties = fmt.Sprintf("%s,title=%s", properties, githubPropertyEscaper.Replace(testCase.Case))
+
+	if testCase.IsWaived() {
+		c.runner.log.Printf("::warning %s::%s", properties, githubEscaper.Replace("waived: "+testCase.waiver.Justification))
+		return
+	}
+
+	message := testCase.stdout
+	if failure := failureMessage(testCase.result); failure != "" {
+		message = failure
+	}
+	if message == "" && testCase.result != nil {
+		message = testCase.result.Error()
+	}
+	c.runner.log.Printf("::error %s::%s", properties, githubEscaper.Replace(message))
+}
+
+// spinner shows the running case and its elapsed time in the last line of the terminal
+type spinner struct {
+	mu     sync.Mutex
+	output io.Writer
+	title  string
+	start  time.Time
+	stop   chan bool
+	done   chan bool
+}
+
+func (p *spinner) run(title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.title, p.start = title, time.Now()
+	p.stop, p.done = make(chan bool), make(chan bool)
+	stop, done := p.stop, p.done
+
+	go func() {
+		frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		defer close(done)
+
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				fmt.Fprintf(p.output, "\r\033[K\033[36m%s  %s, %s\033[0m", frames[i%len(frames)], p.title, time.Since(p.start).Truncate(time.Second))
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// clear locks the terminal and erases the spinner line, so a line can be printed instead of it
+func (p *spinner) clear() {
+	p.mu.Lock()
+	if p.stop != nil {
+		fmt.Fprint(p.output, "\r\033[K")
+	}
+}
+
+func (p *spinner) unlock() {
+	p.mu.Unlock()
+}
+
+func (p *spinner) finish() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+
+	p.clear()
+	p.stop = nil
+	p.unlock()
+}
+
+// caseLine finds the line of the case definition in the suite file, 0 if it's not found
+func caseLine(fileName string, title string) int {
+	f, err := os.Open(fileName)
+	if err != nil || title == "" {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.Contains(text, "case:") && strings.Contains(text, title) {
+			return line
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,159 @@
+package checkup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const reportingSuite = `
+name: Reporting
+cases:
+- case: Passing
+  script: |
+    true
+- case: Failing
+  script: |
+    broken
+- case: Skipped
+  skip: true
+  script: |
+    true
+`
+
+// reportedOutput runs the reporting suite with the reporter, the console output is returned without colors
+func reportedOutput(t *testing.T, name string) string {
+	t.Helper()
+	reporter, err := NewReporter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		if script == "broken" {
+			return "not ok", exitError(1)
+		}
+		return "ok", nil
+	}}
+
+	var output bytes.Buffer
+	s := loadSuite(t, reportingSuite)
+	s.FileName = "report.yaml"
+	r := NewRunner(Options{Executor: executor, Reporter: reporter, Output: NoColors(&output)})
+	r.Run([]*Suite{r.Expand(s)})
+	return output.String()
+}
+
+func TestReporters(t *testing.T) {
+	const total = "1 (of 3) tests passed, 1 tests failed, 1 tests skipped, rated as 50.00%"
+
+	tests := []struct {
+		name     string
+		reporter string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "default",
+			reporter: "default",
+			want: []string{
+				"[ Reporting ], 1..3 tests, file: report.yaml\n",
+				"success  1/3  Passing",
+				"FAILURE  2/3  Failing",
+				"-  3/3  Skipped, skipping reason: 'skip=true' setting",
+				total,
+			},
+			notWant: []string{"::error"},
+		},
+		{
+			name:     "empty name is default",
+			reporter: "",
+			want:     []string{"success  1/3  Passing", total},
+			notWant:  []string{"::error"},
+		},
+		{
+			name:     "dots",
+			reporter: "dots",
+			want:     []string{"[ Reporting ] .FS\n", "FAILURE  2/3  Failing", total},
+			notWant:  []string{"Passing", "Skipped"},
+		},
+		{
+			name:     "github actions",
+			reporter: "github-actions",
+			want:     []string{"FAILURE  2/3  Failing", "::error file=report.yaml,title=Failing::not ok\n", total},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := reportedOutput(t, tt.reporter)
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(output, notWant) {
+					t.Errorf("output contains %q:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	if output := reportedOutput(t, "json"); output != "" {
+		t.Errorf("output = %q, want nothing besides the event stream", output)
+	}
+}
+
+func TestNewReporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"default", false},
+		{"dots", false},
+		{"json", false},
+		{"github-actions", false},
+		{"", false},
+		{"junit", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter, err := NewReporter(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewReporter(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && reporter == nil {
+				t.Errorf("NewReporter(%q) = nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestNoColors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"passed", "\033[32m✓\033[0m  1/2  Passing\n", "success  1/2  Passing\n"},
+		{"failed", "\033[31m✗\033[0m  2/2  Failing\n", "FAILURE  2/2  Failing\n"},
+		{"plain", "[ Suite ], 1..2 tests\n", "[ Suite ], 1..2 tests\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			n, err := NoColors(&output).Write([]byte(tt.input))
+			if err != nil || n != len(tt.input) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(tt.input))
+			}
+			if output.String() != tt.want {
+				t.Errorf("output = %q, want %q", output.String(), tt.want)
+			}
+		})
+	}
+}
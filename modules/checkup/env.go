@@ -0,0 +1,178 @@
+package checkup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sbeliakou/check-up/modules/dotenv"
+	"github.com/sbeliakou/check-up/modules/resolve"
+)
+
+// Env policy modes
+const (
+	EnvInherit   = "inherit"
+	EnvIsolated  = "isolated"
+	EnvAllowlist = "allowlist"
+)
+
+// EnvPolicy tells which variables of check-up process environment the scripts get: all of them (inherit, default),
+// none (isolated), or the allowlist of names and glob patterns, e.g. [PATH, HOME, LC_*]
+type EnvPolicy struct {
+	Mode      string
+	Allowlist []string
+}
+
+// UnmarshalYAML accepts 'inherit', 'isolated' or {allowlist: [...]}
+func (p *EnvPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var mode string
+	if err := unmarshal(&mode); err == nil {
+		if mode != EnvInherit && mode != EnvIsolated {
+			return fmt.Errorf("unknown env_policy '%s', expected one of: inherit, isolated, {allowlist: [...]}", mode)
+		}
+		*p = EnvPolicy{Mode: mode}
+		return nil
+	}
+
+	var allowlist struct {
+		Allowlist []string `yaml:"allowlist"`
+	}
+	if err := unmarshal(&allowlist); err != nil {
+		return err
+	}
+	*p = EnvPolicy{Mode: EnvAllowlist, Allowlist: allowlist.Allowlist}
+	return nil
+}
+
+// ParseEnvPolicy reads the policy of the command line option: inherit, isolated or allowlist:PATH,HOME,...
+func ParseEnvPolicy(value string) (*EnvPolicy, error) {
+	mode, list, _ := strings.Cut(value, ":")
+	switch mode {
+	case EnvInherit, EnvIsolated:
+		if list == "" {
+			return &EnvPolicy{Mode: mode}, nil
+		}
+	case EnvAllowlist:
+		p := &EnvPolicy{Mode: EnvAllowlist}
+		for _, v := range strings.Split(list, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				p.Allowlist = append(p.Allowlist, v)
+			}
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown env policy '%s', expected one of: inherit, isolated, allowlist:NAME,...", value)
+}
+
+func (p *EnvPolicy) String() string {
+	switch {
+	case p == nil || p.Mode == "":
+		return EnvInherit
+	case p.Mode == EnvAllowlist:
+		return fmt.Sprintf("allowlist [%s]", strings.Join(p.Allowlist, ", "))
+	}
+	return p.Mode
+}
+
+// limited tells whether the policy passes only a part of the process environment, or none of it
+func (p *EnvPolicy) limited() bool {
+	return p != nil && (p.Mode == EnvIsolated || p.Mode == EnvAllowlist)
+}
+
+// inherited is the part of the process environment passed to the scripts
+func (p *EnvPolicy) inherited() map[string]string {
+	result := map[string]string{}
+	if p != nil && p.Mode == EnvIsolated {
+		return result
+	}
+
+	for _, v := range os.Environ() {
+		key, value, _ := strings.Cut(v, "=")
+		if p == nil || p.Mode != EnvAllowlist {
+			result[key] = value
+			continue
+		}
+		for _, pattern := range p.Allowlist {
+			if matched, _ := path.Match(pattern, key); matched {
+				result[key] = value
+				break
+			}
+		}
+	}
+	return result
+}
+
+// environment builds the effective environment of the case, sorted by names; the layers override each other as
+// process (filtered by the env policy) < suite envFiles < suite env < case envFiles < case env < loop item,
+// the declared variables are all but the process ones; the error fails the case up front
+func (s *Case) environment(r *Runner) (env []string, declared []string, envErr error) {
+	envErr = s.envError
+	s.errors = nil
+
+	values := s.EnvPolicy.inherited()
+	defined := map[string]string{}
+	for key, value := range s.suiteEnv {
+		defined[key] = value
+	}
+
+	if envErr == nil && len(s.EnvFiles) > 0 {
+		var fileValues map[string]string
+		fileValues, envErr = dotenv.Load(s.EnvFiles, func(key string) (string, bool) {
+			if value, ok := defined[key]; ok {
+				return value, true
+			}
+			value, ok := values[key]
+			return value, ok
+		})
+		for key, value := range fileValues {
+			defined[key] = value
+		}
+	}
+
+	// case env includes the loop item
+	for key, value := range s.Env {
+		defined[key] = value
+	}
+
+	// unresolved references are warnings, unless they're required
+	for key, value := range defined {
+		resolved, err := r.resolver.Value(value)
+		var refErr *resolve.Error
+		switch {
+		case errors.As(err, &refErr) && refErr.Required:
+			if envErr == nil {
+				envErr = fmt.Errorf("%s: %v", key, err)
+			}
+		case err != nil:
+			s.errors = append(s.errors, fmt.Errorf("%s: %v", key, err))
+		}
+		values[key] = resolved
+		declared = append(declared, fmt.Sprintf("%s=%s", key, resolved))
+	}
+
+	// the empty list isn't nil, otherwise the scripts would get the whole process environment
+	env = []string{}
+	for key, value := range values {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(env)
+	sort.Strings(declared)
+
+	sort.Slice(s.errors, func(i, j int) bool { return s.errors[i].Error() < s.errors[j].Error() })
+	return env, declared, envErr
+}
+
+// declared are the variables defined by the suite and the case, as they're written in YAML
+func (s *Case) declared() map[string]string {
+	result := map[string]string{}
+	for key, value := range s.suiteEnv {
+		result[key] = value
+	}
+	for key, value := range s.Env {
+		result[key] = value
+	}
+	return result
+}
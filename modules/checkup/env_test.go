@@ -0,0 +1,203 @@
+package checkup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentPrecedence(t *testing.T) {
+	// every variable is set by the layers up to the one it's named after, the last of them wins
+	t.Setenv("CHECKUP_PROCESS", "process")
+	t.Setenv("CHECKUP_SUITE_FILE", "process")
+	t.Setenv("CHECKUP_SUITE_ENV", "process")
+	t.Setenv("CHECKUP_CASE_FILE", "process")
+	t.Setenv("CHECKUP_CASE_ENV", "process")
+	t.Setenv("item", "process")
+
+	dir := t.TempDir()
+	suiteFile, caseFile := filepath.Join(dir, "suite.env"), filepath.Join(dir, "case.env")
+	files := map[string]string{
+		suiteFile: "CHECKUP_SUITE_FILE=suite file\nCHECKUP_SUITE_ENV=suite file\nCHECKUP_CASE_FILE=suite file\n" +
+			"CHECKUP_CASE_ENV=suite file\nitem=suite file\nSUITE_REF=${CHECKUP_PROCESS:-unset}\n",
+		caseFile: "CHECKUP_CASE_FILE=case file\nCHECKUP_CASE_ENV=case file\nitem=case file\nCASE_REF=${CHECKUP_PROCESS:-unset}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	text := fmt.Sprintf(`
+name: Precedence
+envFiles: [%s]
+env:
+  CHECKUP_SUITE_ENV: suite env
+  CHECKUP_CASE_FILE: suite env
+  CHECKUP_CASE_ENV: suite env
+  item: suite env
+cases:
+- case: Layers
+  envFiles: [%s]
+  env:
+    CHECKUP_CASE_ENV: case env
+    item: case env
+  loop:
+    items: [loop item]
+  script: |
+    true
+`, suiteFile, caseFile)
+
+	layers := map[string]string{
+		"CHECKUP_SUITE_FILE": "suite file",
+		"CHECKUP_SUITE_ENV":  "suite env",
+		"CHECKUP_CASE_FILE":  "case file",
+		"CHECKUP_CASE_ENV":   "case env",
+		"item":               "loop item",
+	}
+
+	tests := []struct {
+		name        string
+		policy      *EnvPolicy
+		wantProcess string
+		wantRef     string
+		wantPath    bool
+	}{
+		{"inherit", nil, "process", "process", true},
+		{"isolated", &EnvPolicy{Mode: EnvIsolated}, "", "unset", false},
+		{"allowlist", &EnvPolicy{Mode: EnvAllowlist, Allowlist: []string{"CHECKUP_*"}}, "process", "process", false},
+		{"allowlist of other variables", &EnvPolicy{Mode: EnvAllowlist, Allowlist: []string{"PATH"}}, "", "unset", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunner(Options{EnvPolicy: tt.policy, Output: &bytes.Buffer{}})
+			s := r.Expand(loadSuite(t, text))
+
+			env, _, err := s.Cases[0].environment(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := map[string]string{}
+			for _, v := range env {
+				key, value, _ := strings.Cut(v, "=")
+				values[key] = value
+			}
+
+			for key, want := range layers {
+				if values[key] != want {
+					t.Errorf("%s = %q, want %q", key, values[key], want)
+				}
+			}
+			if values["CHECKUP_PROCESS"] != tt.wantProcess {
+				t.Errorf("CHECKUP_PROCESS = %q, want %q", values["CHECKUP_PROCESS"], tt.wantProcess)
+			}
+			if values["SUITE_REF"] != tt.wantRef || values["CASE_REF"] != tt.wantRef {
+				t.Errorf("SUITE_REF = %q, CASE_REF = %q, want %q", values["SUITE_REF"], values["CASE_REF"], tt.wantRef)
+			}
+			if _, ok := values["PATH"]; ok != tt.wantPath {
+				t.Errorf("PATH is passed = %v, want %v", ok, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestSuiteEnvFilesCasePolicy(t *testing.T) {
+	t.Setenv("CHECKUP_HOST_VALUE", "host")
+
+	envFile := filepath.Join(t.TempDir(), "suite.env")
+	if err := os.WriteFile(envFile, []byte("FROM_HOST=${CHECKUP_HOST_VALUE}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text := fmt.Sprintf(`
+name: Policies
+envFiles: [%s]
+cases:
+- case: Inherited
+  script: |
+    true
+- case: Isolated
+  env_policy: isolated
+  script: |
+    true
+`, envFile)
+
+	r := NewRunner(Options{Output: &bytes.Buffer{}})
+	s := r.Expand(loadSuite(t, text))
+
+	for i, want := range []string{"FROM_HOST=host", "FROM_HOST="} {
+		_, declared, err := s.Cases[i].environment(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(declared, " ") != want {
+			t.Errorf("case '%s': declared = %v, want %s", s.Cases[i].Case, declared, want)
+		}
+	}
+}
+
+func TestEffectiveEnvOutput(t *testing.T) {
+	t.Setenv("CHECKUP_ALLOWED", "allowed")
+	t.Setenv("CHECKUP_ALLOWED_TOKEN", "host-token-value")
+	t.Setenv("CHECKUP_HIDDEN", "hidden")
+
+	const text = `
+name: Effective env
+cases:
+- case: Failing
+  env:
+    DB_PASSWORD: hunter22
+  script: |
+    false
+`
+
+	tests := []struct {
+		name    string
+		policy  *EnvPolicy
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "allowlist",
+			policy:  &EnvPolicy{Mode: EnvAllowlist, Allowlist: []string{"CHECKUP_ALLOWED*"}},
+			want:    []string{"env_policy: allowlist [CHECKUP_ALLOWED*]", "CHECKUP_ALLOWED=allowed", "CHECKUP_ALLOWED_TOKEN=***", "DB_PASSWORD=***"},
+			notWant: []string{"CHECKUP_HIDDEN", "host-token-value", "hunter22"},
+		},
+		{
+			name:    "isolated",
+			policy:  &EnvPolicy{Mode: EnvIsolated},
+			want:    []string{"env_policy: isolated", "DB_PASSWORD=***"},
+			notWant: []string{"CHECKUP_ALLOWED", "hunter22"},
+		},
+		{
+			name:    "inherit",
+			policy:  &EnvPolicy{Mode: EnvInherit},
+			want:    []string{"env_policy: inherit", "DB_PASSWORD=***"},
+			notWant: []string{"CHECKUP_ALLOWED", "CHECKUP_HIDDEN", "hunter22"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+				return "", exitError(1)
+			}}
+			_, output := runSuite(t, text, Options{Executor: executor, EnvPolicy: tt.policy, Verbosity: 4})
+
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(output, notWant) {
+					t.Errorf("output contains %q:\n%s", notWant, output)
+				}
+			}
+		})
+	}
+}
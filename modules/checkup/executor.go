@@ -0,0 +1,55 @@
+package checkup
+
+import (
+	"github.com/sbeliakou/check-up/modules/bash"
+)
+
+// Executor runs a script in the working directory with the timeout (seconds, 0 - no timeout)
+// and environment, passing output lines to onLine as they come when it's set
+type Executor interface {
+	Run(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error)
+}
+
+// StdoutExecutor is an Executor which also returns stdout of the script without stderr,
+// 'expect' assertions are evaluated against it; otherwise they get the whole output
+type StdoutExecutor interface {
+	Executor
+	RunStdout(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, []byte, error)
+}
+
+// Bash runs the scripts with bash and check-up helper functions: run, assert_*, skip, fail
+type Bash struct{}
+
+func (Bash) Run(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error) {
+	return bash.RunBashScriptStream(script, workdir, timeout, env, onLine)
+}
+
+func (Bash) RunStdout(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, []byte, error) {
+	return bash.RunScriptStdout("/bin/bash", script, workdir, timeout, env, onLine)
+}
+
+// Interpreter runs the scripts with the command, e.g. "python3" or "pwsh -NoProfile", passing
+// the script file as its last argument, check-up helper functions are only available to bash
+type Interpreter struct {
+	Command string
+}
+
+func (i Interpreter) Run(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error) {
+	return bash.RunScript(i.Command, script, workdir, timeout, env, onLine)
+}
+
+func (i Interpreter) RunStdout(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, []byte, error) {
+	return bash.RunScriptStdout(i.Command, script, workdir, timeout, env, onLine)
+}
+
+// executor picks the executor of the case 'shell' setting: the runner one for bash,
+// one of Options.Executors by its name, or the interpreter command
+func (r *Runner) executor(shell string) Executor {
+	if e, ok := r.Executors[shell]; ok {
+		return e
+	}
+	if shell == "" || shell == "bash" {
+		return r.Executor
+	}
+	return Interpreter{Command: shell}
+}
@@ -0,0 +1,93 @@
+package checkup
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecutor(t *testing.T) {
+	custom := &stubExecutor{}
+	python := &stubExecutor{}
+	r := NewRunner(Options{Executor: custom, Executors: map[string]Executor{"python3": python}, Output: &bytes.Buffer{}})
+
+	tests := []struct {
+		shell string
+		want  Executor
+	}{
+		{"", custom},
+		{"bash", custom},
+		{"python3", python},
+		{"pwsh -NoProfile", Interpreter{Command: "pwsh -NoProfile"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			if got := r.executor(tt.shell); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("executor(%q) = %#v, want %#v", tt.shell, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultExecutor(t *testing.T) {
+	if _, ok := NewRunner(Options{Output: &bytes.Buffer{}}).executor("").(Bash); !ok {
+		t.Error("default executor is not Bash")
+	}
+}
+
+func TestCaseShell(t *testing.T) {
+	const text = `
+name: Shells
+cases:
+- case: Bash
+  script: |
+    bash script
+- case: Python
+  shell: python3
+  script: |
+    python script
+- case: Interpreter alias
+  interpreter: python3
+  script: |
+    another python script
+`
+
+	bashScripts := &stubExecutor{}
+	pythonScripts := &stubExecutor{}
+	runSuite(t, text, Options{Executor: bashScripts, Executors: map[string]Executor{"python3": pythonScripts}})
+
+	if got := strings.Join(bashScripts.scripts, ","); got != "bash script" {
+		t.Errorf("bash scripts = %s, want bash script", got)
+	}
+	if got := strings.Join(pythonScripts.scripts, ","); got != "python script,another python script" {
+		t.Errorf("python scripts = %s, want both python scripts", got)
+	}
+}
+
+func TestInterpreter(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{"sh", "sh", "echo $((1 + 2))", "3", false},
+		{"arguments", "sh -e", "false\necho unreachable", "", true},
+		{"no command", "", "echo 1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, err := Interpreter{Command: tt.command}.Run(tt.script, t.TempDir(), 10, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.TrimSpace(string(stdout)); got != tt.want {
+				t.Errorf("Run() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/expect"
+	"github.com/sbeliakou/check-up/modules/jUnit"
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/snapshot"
+)
+
+type junitProperty struct {
+	Name  string
+	Value string
+}
+
+type junitCase struct {
+	Name       string
+	Time       string
+	Status     string
+	Output     string
+	Properties []junitProperty
+}
+
+type junitSuite struct {
+	Name       string
+	Tests      int
+	Failures   int
+	Skipped    int
+	Time       string
+	TimeStamp  string
+	Properties []junitProperty
+	Cases      []junitCase
+}
+
+func (c *Suite) junitReport() junitSuite {
+	suite := junitSuite{
+		Name:      c.Name,
+		Tests:     c.all,
+		Failures:  c.failed,
+		Skipped:   c.skipped + c.waived,
+		Time:      c.durationString,
+		TimeStamp: c.startTime.Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "rating", Value: fmt.Sprintf("%.2f", c.score)},
+		},
+		Cases: []junitCase{},
+	}
+
+	for _, category := range c.summary.Categories {
+		suite.Properties = append(suite.Properties, junitProperty{
+			Name:  fmt.Sprintf("category.%s.rating", category.Name),
+			Value: fmt.Sprintf("%.2f", category.Rating),
+		})
+	}
+
+	for _, id := range c.getScenarioIds() {
+		testCase := c.Cases[id]
+		if !testCase.CanShow() {
+			continue
+		}
+
+		t := junitCase{
+			Name:   testCase.Case,
+			Time:   testCase.durationString,
+			Status: "failed",
+			Output: testCase.stdout,
+		}
+
+		if failure := failureMessage(testCase.result); failure != "" {
+			t.Output = failure + "\n\n" + testCase.stdout
+		}
+
+		switch {
+		case testCase.Skip:
+			t.Status, t.Output = "skipped", testCase.skipReason
+		case testCase.IsSuccessful():
+			t.Status = "passed"
+		case testCase.IsWaived():
+			t.Status, t.Output = "skipped", fmt.Sprintf("waived: %s", testCase.waiver.Justification)
+			t.Properties = append(t.Properties,
+				junitProperty{Name: "waiver_owner", Value: testCase.waiver.Owner},
+				junitProperty{Name: "waiver_expires", Value: testCase.waiver.Expires},
+			)
+		}
+
+		for _, p := range []junitProperty{
+			{Name: "category", Value: testCase.Category},
+			{Name: "severity", Value: testCase.Severity},
+			{Name: "control_id", Value: testCase.ControlID},
+			{Name: "level", Value: testCase.Level},
+			{Name: "rationale", Value: strings.TrimSpace(testCase.Rationale)},
+			{Name: "audit", Value: strings.TrimSpace(testCase.Audit)},
+			{Name: "remediation", Value: strings.TrimSpace(testCase.Remediation)},
+			{Name: "remediation_status", Value: testCase.remediation.state},
+		} {
+			if p.Value != "" {
+				t.Properties = append(t.Properties, p)
+			}
+		}
+		for _, v := range testCase.References {
+			t.Properties = append(t.Properties, junitProperty{Name: "reference", Value: v})
+		}
+
+		t.Name, t.Output = c.mask(t.Name), c.mask(t.Output)
+
+		suite.Cases = append(suite.Cases, t)
+	}
+
+	return suite
+}
+
+// failureMessage explains the failure of 'expect' assertions, the snapshot, or the case setup, e.g. a broken env file;
+// it's empty when the script failed with its exit code
+func failureMessage(err error) string {
+	var expectFailure *expect.Failure
+	var snapshotFailure *snapshot.Failure
+	switch {
+	case errors.As(err, &expectFailure):
+		return expectFailure.Message
+	case errors.As(err, &snapshotFailure):
+		return snapshotFailure.Message
+	case err != nil && exitCode(err) == -1:
+		return err.Error()
+	}
+	return ""
+}
+
+// JUnit renders JUnit XML report of the run suites
+func JUnit(d []*Suite) ([]byte, error) {
+	T := struct {
+		Suites []junitSuite
+	}{}
+
+	for _, c := range d {
+		T.Suites = append(T.Suites, c.junitReport())
+	}
+
+	funcMap := template.FuncMap{
+		"Escape": strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace,
+	}
+
+	var buf bytes.Buffer
+	jut, err := template.New("junit report").Funcs(funcMap).Parse(string(jUnit.JUnitTemplate))
+	if err != nil {
+		return nil, err
+	}
+	if err := jut.Execute(&buf, T); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// caseIDs returns stable identifiers of the cases: file, control id or case title, and loop item,
+// cases having the same identifier in a suite get '#n' suffix
+func (c *Suite) caseIDs() map[int]string {
+	source := c.FileName
+	if source == "" {
+		source = c.Name
+	} else if !c.remote() {
+		source = filepath.Clean(source)
+	}
+
+	result := map[int]string{}
+	seen := map[string]int{}
+
+	for id, testCase := range c.Cases {
+		key := testCase.ControlID
+		if key == "" {
+			key = testCase.title
+		}
+
+		caseID := fmt.Sprintf("%s::%s", source, key)
+		if testCase.item != "" {
+			caseID = fmt.Sprintf("%s[%s]", caseID, testCase.item)
+		}
+
+		seen[caseID]++
+		if seen[caseID] > 1 {
+			caseID = fmt.Sprintf("%s#%d", caseID, seen[caseID])
+		}
+
+		result[id] = caseID
+	}
+
+	return result
+}
+
+// Report is the JSON report of the run suite, script output is included at verbosity 2 for failed cases, and at 3 for all
+func (c *Suite) Report() report.Suite {
+	var jsonReportData report.Suite
+	jsonReportData.TestName = c.Name
+	jsonReportData.File = c.FileName
+	jsonReportData.Tests = []report.Test{}
+
+	ids := c.caseIDs()
+	verbosity := 0
+	if c.runner != nil {
+		verbosity = c.runner.Verbosity
+	}
+
+	if c.getScenarioCount() > 0 {
+		for _, id := range c.getScenarioIds() {
+			if c.Cases[id].CanShow() {
+				t := report.Test{
+					ID:       ids[id],
+					Name:     c.Cases[id].Case,
+					Item:     c.Cases[id].item,
+					Status:   c.Cases[id].IsSuccessful(),
+					Skipped:  c.Cases[id].Skip,
+					Duration: c.Cases[id].durationString,
+					Weight:   c.Cases[id].Weight,
+					Category: c.Cases[id].Category,
+					Severity: c.Cases[id].Severity,
+
+					ControlID:   c.Cases[id].ControlID,
+					Level:       c.Cases[id].Level,
+					Rationale:   strings.TrimSpace(c.Cases[id].Rationale),
+					Audit:       strings.TrimSpace(c.Cases[id].Audit),
+					Remediation: strings.TrimSpace(c.Cases[id].Remediation),
+					References:  c.Cases[id].References,
+
+					RemediationStatus: c.Cases[id].remediation.state,
+
+					Waived: c.Cases[id].waiver,
+
+					DurationMilliSeconds: c.Cases[id].durationMilliSeconds,
+				}
+
+				if (verbosity > 1 && c.Cases[id].IsFailed()) || (verbosity > 2) {
+					t.Stdout = c.Cases[id].stdout
+				}
+
+				// assertions explain the failure better than the output, they're reported at any verbosity
+				if !c.Cases[id].IsSuccessful() || verbosity > 2 {
+					t.Expectations = c.Cases[id].expectations
+				}
+				t.Snapshot = c.Cases[id].snapshotResult
+
+				t.Name, t.Item, t.Stdout = c.mask(t.Name), c.mask(t.Item), c.mask(t.Stdout)
+				t.Expectations = c.maskExpectations(t.Expectations)
+				if t.Snapshot != nil {
+					masked := *t.Snapshot
+					masked.Diff = c.mask(masked.Diff)
+					t.Snapshot = &masked
+				}
+
+				jsonReportData.Tests = append(jsonReportData.Tests, t)
+			}
+		}
+	}
+
+	jsonReportData.Summary = report.Summary{
+		Success:    c.successfull,
+		Failed:     c.failed,
+		Skipped:    c.skipped,
+		Waived:     c.waived,
+		Rating:     c.score,
+		Duration:   c.durationString,
+		Categories: c.summary.Categories,
+
+		DurationMilliSeconds: c.durationMilliSeconds,
+	}
+
+	return jsonReportData
+}
+
+// mask hides the secret values of the run in the report text
+func (c *Suite) mask(text string) string {
+	if c.runner == nil {
+		return text
+	}
+	return c.runner.masker.String(text)
+}
+
+// maskExpectations hides the secret values of the expected and actual values, which aren't strings
+// are replaced with the masked JSON text when it has secrets
+func (c *Suite) maskExpectations(results []expect.Result) []expect.Result {
+	value := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return c.mask(s)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		if masked := c.mask(string(data)); masked != string(data) {
+			return masked
+		}
+		return v
+	}
+
+	masked := []expect.Result{}
+	for _, r := range results {
+		r.Query, r.Expected, r.Actual, r.Error = c.mask(r.Query), value(r.Expected), value(r.Actual), c.mask(r.Error)
+		masked = append(masked, r)
+	}
+	return masked
+}
+
+// Report collects the reports of the run suites with the grand total and waivers,
+// the baseline comparison is left to the caller
+func (r *Runner) Report(d []*Suite) report.Run {
+	var run report.Run
+	results := []scoring.Result{}
+	total := 0
+
+	for _, c := range d {
+		run.Suites = append(run.Suites, c.Report())
+		results = append(results, c.scoringResults()...)
+		total += c.durationMilliSeconds
+	}
+
+	if len(d) == 1 {
+		run.Summary = run.Suites[0].Summary
+	} else {
+		summary := scoring.Calculate(results)
+		run.Summary = report.Summary{
+			Success:    summary.Passed,
+			Failed:     summary.Failed,
+			Skipped:    summary.Skipped,
+			Waived:     summary.Waived,
+			Rating:     summary.Rating,
+			Duration:   (time.Duration(total) * time.Millisecond).String(),
+			Categories: summary.Categories,
+
+			DurationMilliSeconds: total,
+		}
+	}
+
+	run.Waivers = r.Waivers
+
+	return run
+}
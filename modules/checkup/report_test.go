@@ -0,0 +1,258 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCaseIDs(t *testing.T) {
+	const text = `
+name: Benchmark
+cases:
+- case: Ensure /tmp is a separate partition
+  control_id: 1.1.2
+  script: |
+    true
+- case: Ensure /tmp is a separate partition, again
+  control_id: 1.1.2
+  script: |
+    true
+- case: No control id
+  script: |
+    true
+- case: Service $item is running
+  loop:
+    items: [nginx, sshd]
+  script: |
+    true
+`
+
+	tests := []struct {
+		name     string
+		fileName string
+		want     []string
+	}{
+		{
+			name:     "file",
+			fileName: "./cis/../cis/1.1.yaml",
+			want: []string{
+				"cis/1.1.yaml::1.1.2",
+				"cis/1.1.yaml::1.1.2#2",
+				"cis/1.1.yaml::No control id",
+				"cis/1.1.yaml::Service $item is running[nginx]",
+				"cis/1.1.yaml::Service $item is running[sshd]",
+			},
+		},
+		{
+			name: "no file",
+			want: []string{
+				"Benchmark::1.1.2",
+				"Benchmark::1.1.2#2",
+				"Benchmark::No control id",
+				"Benchmark::Service $item is running[nginx]",
+				"Benchmark::Service $item is running[sshd]",
+			},
+		},
+		{
+			name:     "remote",
+			fileName: "https://example.com/cis//1.1.yaml",
+			want: []string{
+				"https://example.com/cis//1.1.yaml::1.1.2",
+				"https://example.com/cis//1.1.yaml::1.1.2#2",
+				"https://example.com/cis//1.1.yaml::No control id",
+				"https://example.com/cis//1.1.yaml::Service $item is running[nginx]",
+				"https://example.com/cis//1.1.yaml::Service $item is running[sshd]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := loadSuite(t, text)
+			s.FileName = tt.fileName
+			ids := NewRunner(Options{Output: &bytes.Buffer{}}).Expand(s).caseIDs()
+
+			got := []string{}
+			for i := 0; i < len(ids); i++ {
+				got = append(got, ids[i])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("ids = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaseIDsStable(t *testing.T) {
+	const text = `
+name: Benchmark
+cases:
+- case: First
+  control_id: "1"
+  script: |
+    true
+- case: Second
+  script: |
+    true
+`
+	// a new case and the filter don't change identifiers of the other cases
+	const extended = text + `- case: Third
+  control_id: "3"
+  script: |
+    true
+`
+
+	ids := func(text string, filter string) []string {
+		r := NewRunner(Options{Filter: filter, Output: &bytes.Buffer{}})
+		result := []string{}
+		for _, test := range r.Expand(loadSuite(t, text)).Report().Tests {
+			result = append(result, test.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		filter string
+		want   []string
+	}{
+		{"original", text, "", []string{"Benchmark::1", "Benchmark::Second"}},
+		{"case added", extended, "", []string{"Benchmark::1", "Benchmark::Second", "Benchmark::3"}},
+		{"filtered", extended, "Second", []string{"Benchmark::Second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.text, tt.filter); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	const text = `
+name: Reporting
+cases:
+- case: Passing
+  control_id: "1.1"
+  severity: high
+  rationale: |
+    Because
+  script: |
+    true
+- case: Failing
+  weight: 2
+  env:
+    API_TOKEN: s3cr3t-value
+  script: |
+    broken
+- case: Skipped
+  skip: true
+  script: |
+    true
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		if script == "broken" {
+			return "token s3cr3t-value is rejected", exitError(1)
+		}
+		return "ok", nil
+	}}
+
+	tests := []struct {
+		verbosity  int
+		wantStdout []string
+	}{
+		{0, []string{"", "", ""}},
+		{2, []string{"", "token *** is rejected", ""}},
+		{3, []string{"ok", "token *** is rejected", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("verbosity %d", tt.verbosity), func(t *testing.T) {
+			s, _ := runSuite(t, text, Options{Executor: executor, Verbosity: tt.verbosity})
+			suite := s.Report()
+
+			if len(suite.Tests) != 3 {
+				t.Fatalf("tests = %d, want 3", len(suite.Tests))
+			}
+			for i, want := range []string{"passed", "failed", "skipped"} {
+				if got := suite.Tests[i].State(); got != want {
+					t.Errorf("test '%s': state = %s, want %s", suite.Tests[i].Name, got, want)
+				}
+				if got := suite.Tests[i].Stdout; got != tt.wantStdout[i] {
+					t.Errorf("test '%s': stdout = %q, want %q", suite.Tests[i].Name, got, tt.wantStdout[i])
+				}
+			}
+
+			passing := suite.Tests[0]
+			if passing.ID != "Reporting::1.1" || passing.Severity != "high" || passing.Rationale != "Because" || passing.Weight != 1 {
+				t.Errorf("test = %+v, want id, severity, rationale and the default weight", passing)
+			}
+			if suite.Summary.Success != 1 || suite.Summary.Failed != 1 || suite.Summary.Skipped != 1 {
+				t.Errorf("summary = %+v, want 1 passed, 1 failed, 1 skipped", suite.Summary)
+			}
+		})
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	const text = `
+name: Reporting <web>
+cases:
+- case: Passing
+  control_id: "1.1"
+  script: |
+    true
+- case: Failing
+  env:
+    API_TOKEN: s3cr3t-value
+  script: |
+    broken
+- case: Skipped
+  skip: true
+  script: |
+    true
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		if script == "broken" {
+			return "token s3cr3t-value is rejected", exitError(1)
+		}
+		return "ok", nil
+	}}
+	s, _ := runSuite(t, text, Options{Executor: executor})
+
+	data, err := JUnit([]*Suite{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Skipped  int    `xml:"skipped,attr"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+
+	if len(suites.Suites) != 1 {
+		t.Fatalf("suites = %d, want 1:\n%s", len(suites.Suites), data)
+	}
+	suite := suites.Suites[0]
+	if suite.Name != "Reporting <web>" || suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("suite = %+v, want 3 tests, 1 failure, 1 skipped", suite)
+	}
+	if strings.Contains(string(data), "s3cr3t-value") || !strings.Contains(string(data), "token *** is rejected") {
+		t.Errorf("report doesn't mask the secret:\n%s", data)
+	}
+}
@@ -0,0 +1,480 @@
+package checkup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/events"
+	"github.com/sbeliakou/check-up/modules/mask"
+	"github.com/sbeliakou/check-up/modules/resolve"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/snapshot"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+// Options of the runner, zero value runs all cases with no timeouts in the current directory
+type Options struct {
+	// Workdir is the working directory of the scripts, the current one if it's empty
+	Workdir string
+	// Timeout of every script in seconds, overrides the cases 'timeout' settings when it's above 0
+	Timeout int
+	// Verbosity level of the console output, 0-4
+	Verbosity int
+
+	// Filter runs only the cases which titles contain it
+	Filter string
+	// Tags runs only the cases having any of comma separated tags
+	Tags string
+	// Controls runs only the cases which control ids match any of comma separated glob patterns
+	Controls string
+	// SkipLoopCommand keeps the cases with 'loop.command' unexpanded, without running the command
+	SkipLoopCommand bool
+
+	// Secrets is the store of {SECRET:name} references in environment values, the process environment by default
+	Secrets resolve.Store
+	// RequireEnv fails the cases which environment has unresolved references, instead of resolving them to empty values
+	RequireEnv bool
+	// EnvPolicy overrides 'env_policy' setting of the suites and cases when it's set
+	EnvPolicy *EnvPolicy
+
+	// UpdateSnapshots records the current output of 'snapshot' cases, replacing the changed snapshots
+	UpdateSnapshots bool
+
+	// SkippedAs overrides 'skipped_as' setting of the suites: exclude, fail or pass
+	SkippedAs string
+	// Waivers are accepted failures
+	Waivers []*waivers.Waiver
+
+	// Remediate runs 'remediate' scripts of failed cases and checks them again
+	Remediate bool
+	// DryRun shows remediation scripts instead of running them
+	DryRun bool
+	// Interactive asks for confirmation before every remediation, answers are read from Input
+	Interactive bool
+	Input       io.Reader
+
+	// Executor runs the scripts, bash by default
+	Executor Executor
+	// Executors are used for the cases which 'shell' setting matches the key, instead of running the interpreter command
+	Executors map[string]Executor
+	// Reporter renders the run on the console, the classic check-up output by default
+	Reporter Reporter
+	// Output is the console, stdout by default, colors can be stripped with NoColors
+	Output io.Writer
+	// Events receives the progress of the run, if it's set
+	Events *events.Writer
+	// Stream prints script output line by line while the case is running
+	Stream bool
+	// Spinner shows the running case with elapsed time, Output has to be a terminal
+	Spinner bool
+}
+
+// Runner expands and runs the suites
+type Runner struct {
+	Options
+
+	log      *log.Logger
+	input    *bufio.Reader
+	progress *spinner
+	resolver *resolve.Resolver
+	masker   *mask.Masker
+}
+
+// NewRunner makes the runner, setting the defaults of empty options
+func NewRunner(opts Options) *Runner {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.Input == nil {
+		opts.Input = os.Stdin
+	}
+	if opts.Executor == nil {
+		opts.Executor = Bash{}
+	}
+	if opts.Reporter == nil {
+		opts.Reporter = &textReporter{}
+	}
+
+	// secrets are masked in everything printed by the runner
+	masker := &mask.Masker{}
+
+	return &Runner{
+		Options:  opts,
+		log:      log.New(masker.Writer(opts.Output), "", 0),
+		input:    bufio.NewReader(opts.Input),
+		progress: &spinner{output: opts.Output},
+		resolver: &resolve.Resolver{Secrets: opts.Secrets, Workdir: opts.Workdir, Timeout: opts.Timeout, Required: opts.RequireEnv, OnSecret: masker.AddSecret},
+		masker:   masker,
+	}
+}
+
+// Run runs the expanded suites one by one, and reports the totals
+func (r *Runner) Run(suites []*Suite) {
+	for _, s := range suites {
+		r.RunSuite(s)
+	}
+	r.Reporter.RunFinished(suites)
+	r.printWaiversWarnings()
+}
+
+// RunSuite runs the cases of the expanded suite
+func (r *Runner) RunSuite(c *Suite) {
+	c.runner = r
+	c.startTime = time.Now()
+	c.ids = c.caseIDs()
+	r.Reporter.SuiteStarted(c)
+	r.emit(events.Event{Type: events.SuiteStarted, Suite: c.Name, File: c.FileName, Tests: c.getScenarioCount()})
+
+	if c.getScenarioCount() > 0 {
+		j := 0
+		for _, id := range c.getScenarioIds() {
+			testCase := &c.Cases[id]
+			if testCase.CanShow() {
+				r.emit(c.caseEvent(events.CaseStarted, id, j+1))
+			}
+
+			c.execTask(id)
+
+			if testCase.CanShow() {
+				e := c.caseEvent(events.CaseFinished, id, j+1)
+				e.Status, e.DurationMilliSeconds = testCase.state(), testCase.durationMilliSeconds
+				if !testCase.Skip {
+					e.ExitCode = events.Code(exitCode(testCase.result))
+				}
+				r.emit(e)
+
+				r.Reporter.CaseFinished(c, id, j+1)
+				j++
+			} else if testCase.Case == "" {
+				r.Reporter.CaseFinished(c, id, j+1)
+			}
+		}
+	}
+
+	c.signOff()
+	summary := c.Report().Summary
+	r.emit(events.Event{Type: events.SuiteFinished, Suite: c.Name, File: c.FileName, Summary: &summary})
+	r.Reporter.SuiteFinished(c)
+}
+
+// run executes the script of the case with its environment
+func (s *Case) run(r *Runner) ([]byte, error) {
+	env, declared, envErr := s.environment(r)
+	s.env, s.declaredEnv = env, declared
+	s.expectations, s.snapshotResult = nil, nil
+
+	for _, v := range s.env {
+		key, value, ok := strings.Cut(v, "=")
+		switch {
+		case ok && mask.Declared(key, s.Secrets):
+			r.masker.AddSecret(value)
+		case ok && mask.Secret(key, s.Secrets):
+			r.masker.Add(value)
+		}
+	}
+
+	// the case fails up front when its environment can't be loaded or resolved
+	if envErr != nil {
+		s.stdout, s.result, s.status = "", envErr, "failed"
+		return nil, envErr
+	}
+
+	var stdout, stdoutOnly []byte
+	var err error
+	executor := r.executor(s.Shell)
+	if s.Script == "" && s.Checks.Defined() {
+		var output string
+		output, err = s.Checks.Run(lookupEnv(s.env), r.Workdir, s.Timeout)
+		stdout = []byte(output)
+	} else if e, ok := executor.(StdoutExecutor); ok && s.Expect.Defined() {
+		// assertions parse stdout only, stderr messages would break JSON and YAML
+		stdout, stdoutOnly, err = e.RunStdout(s.Script, r.Workdir, s.Timeout, s.env, r.streamOutput(s.label()))
+	} else {
+		stdout, err = executor.Run(s.Script, r.Workdir, s.Timeout, s.env, r.streamOutput(s.label()))
+		stdoutOnly = stdout
+	}
+	s.stdout = strings.TrimSpace(string(stdout))
+	s.result = err
+
+	if err == nil && s.Expect.Defined() {
+		s.expectations, err = s.Expect.Check(strings.TrimSpace(string(stdoutOnly)), lookupEnv(s.env))
+		s.result = err
+	}
+	if err == nil && s.Snapshot && s.snapshotFile != "" {
+		var result snapshot.Result
+		result, err = snapshot.Compare(s.snapshotFile, snapshot.Normalize(r.masker.String(s.stdout), s.Normalize), r.UpdateSnapshots)
+		s.snapshotResult = &result
+		s.result = err
+	}
+
+	if err == nil {
+		s.status = "success"
+	} else {
+		s.status = "failed"
+
+		if s.Debug.Script != "" {
+			debugStartTime := time.Now()
+			debugStdout, debugErr := r.executor(s.Shell).Run(s.Debug.Script, r.Workdir, s.Debug.Timeout, s.env, r.streamOutput(s.label()+", debug"))
+			s.Debug.stdout = strings.TrimSpace(string(debugStdout))
+			s.Debug.result = debugErr
+			_, s.Debug.durationMilliSeconds = duration(debugStartTime, time.Now())
+		}
+	}
+
+	return stdout, err
+}
+
+// lookupEnv resolves variables of native checks and 'expect' settings from the effective environment of the case,
+// the process variables are there as far as the env policy lets them
+func lookupEnv(env []string) func(string) string {
+	values := map[string]string{}
+	for _, v := range env {
+		if key, value, ok := strings.Cut(v, "="); ok {
+			values[key] = value
+		}
+	}
+
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+// label is the case title, or the task name for before/after tasks
+func (s *Case) label() string {
+	if s.Case != "" {
+		return s.Case
+	}
+	return s.Name
+}
+
+// streamOutput prints script output lines with the task label and timestamp as they come, when streaming is on
+func (r *Runner) streamOutput(label string) func(string) {
+	if !r.Stream {
+		return nil
+	}
+
+	return func(line string) {
+		r.progress.clear()
+		r.print(fmt.Sprintf("  %s [%s] %s", time.Now().Format("15:04:05"), label, line))
+		r.progress.unlock()
+	}
+}
+
+func (c *Suite) execTask(item int) {
+	testCase := &c.Cases[item]
+	r := c.runner
+
+	if testCase.Skip {
+		testCase.skipReason = "'skip=true' setting"
+		return
+	}
+
+	if testCase.empty() {
+		testCase.skipReason = "empty 'script' setting"
+		testCase.Skip = true
+		return
+	}
+
+	taskStartTime := time.Now()
+
+	if r.Spinner && !r.Interactive && testCase.CanShow() {
+		r.progress.run(testCase.Case)
+		defer r.progress.finish()
+	}
+
+	for _, name := range testCase.Before {
+		c.runTask(item, c.getIdByName(name), events.TaskBefore)
+	}
+
+	if testCase.Snapshot && testCase.CanShow() {
+		testCase.snapshotFile = c.snapshotFile(item)
+	}
+
+	testCase.run(r)
+	if testCase.IsFailed() && testCase.Debug.Script != "" {
+		c.emitTask(item, events.TaskDebug, "debug", testCase.Debug.result, testCase.Debug.durationMilliSeconds)
+	}
+
+	for _, name := range testCase.After {
+		c.runTask(item, c.getIdByName(name), events.TaskAfter)
+	}
+
+	if r.Remediate && testCase.IsFailed() {
+		c.remediate(item)
+	}
+
+	if testCase.CanShow() {
+		c.applyWaiver(item)
+	}
+
+	testCase.durationString, testCase.durationMilliSeconds = duration(taskStartTime, time.Now())
+}
+
+// snapshotFile is the snapshot path of the case, named after its id without the suite file,
+// snapshots of remote suites are kept in the working directory
+func (c *Suite) snapshotFile(item int) string {
+	suiteFile := c.FileName
+	if suiteFile == "" {
+		suiteFile = c.Name
+	} else if c.remote() {
+		suiteFile = path.Base(suiteFile)
+	}
+
+	_, key, _ := strings.Cut(c.ids[item], "::")
+	return snapshot.Path(suiteFile, key)
+}
+
+// runTask runs before/after task of the case
+func (c *Suite) runTask(item int, task int, kind string) {
+	startTime := time.Now()
+	c.Cases[task].run(c.runner)
+
+	_, milliSeconds := duration(startTime, time.Now())
+	c.emitTask(item, kind, c.Cases[task].Name, c.Cases[task].result, milliSeconds)
+}
+
+// exitCode of the script result, -1 when the script didn't exit normally
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// emit writes the event to the event stream, if it's set
+func (r *Runner) emit(e events.Event) {
+	if r.Events != nil {
+		e.Case, e.Item = r.masker.String(e.Case), r.masker.String(e.Item)
+		r.Events.Emit(e)
+	}
+}
+
+// caseEvent makes the event of the case with its id and index
+func (c *Suite) caseEvent(kind string, item int, index int) events.Event {
+	testCase := &c.Cases[item]
+	return events.Event{
+		Type:  kind,
+		Suite: c.Name,
+		File:  c.FileName,
+		ID:    c.ids[item],
+		Index: strings.TrimSpace(c.caseIndex(item, index)),
+		Case:  testCase.Case,
+		Item:  testCase.item,
+	}
+}
+
+func (c *Suite) emitTask(item int, kind string, name string, result error, milliSeconds int) {
+	if c.runner.Events == nil {
+		return
+	}
+
+	status := scoring.StatusPassed
+	if result != nil {
+		status = scoring.StatusFailed
+	}
+
+	c.runner.emit(events.Event{
+		Type:                 events.TaskFinished,
+		Suite:                c.Name,
+		File:                 c.FileName,
+		ID:                   c.ids[item],
+		Case:                 c.Cases[item].Case,
+		Item:                 c.Cases[item].item,
+		Kind:                 kind,
+		Task:                 name,
+		Status:               status,
+		DurationMilliSeconds: milliSeconds,
+		ExitCode:             events.Code(exitCode(result)),
+	})
+}
+
+// applyWaiver marks the failed case as waived if there's an active waiver for it
+func (c *Suite) applyWaiver(item int) {
+	testCase := &c.Cases[item]
+
+	w := waivers.Find(c.runner.Waivers, testCase.Case, testCase.ControlID, testCase.Tags)
+	if w == nil || !testCase.IsFailed() {
+		return
+	}
+	w.Matched++
+
+	if w.Expired {
+		testCase.errors = append(testCase.errors, fmt.Errorf("waiver '%s' expired on %s", w, w.Expires))
+		return
+	}
+
+	testCase.waiver = w
+	testCase.status = "waived"
+}
+
+// Remediation states
+const (
+	RemediationFixed         = "fixed"
+	RemediationStillFailing  = "still failing"
+	RemediationNotRemediable = "not remediable"
+	RemediationDryRun        = "dry run"
+	RemediationDeclined      = "declined"
+)
+
+// remediate runs 'remediate' script of the failed case and checks it again
+func (c *Suite) remediate(item int) {
+	testCase := &c.Cases[item]
+	r := c.runner
+
+	if strings.TrimSpace(testCase.Remediate) == "" {
+		testCase.remediation.state = RemediationNotRemediable
+		return
+	}
+
+	if r.DryRun {
+		testCase.remediation.state = RemediationDryRun
+		return
+	}
+
+	if r.Interactive {
+		fmt.Fprintf(r.Output, "Case '%s' failed, run remediation script?\n  %s\n[y/N]: ", testCase.Case, regexp.MustCompile(`\n`).ReplaceAllString(strings.TrimSpace(testCase.Remediate), "\n  "))
+		answer, _ := r.input.ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			testCase.remediation.state = RemediationDeclined
+			return
+		}
+	}
+
+	remediationStartTime := time.Now()
+	stdout, err := r.executor(testCase.Shell).Run(testCase.Remediate, r.Workdir, testCase.Timeout, testCase.env, r.streamOutput(testCase.label()+", remediate"))
+	testCase.remediation.stdout = strings.TrimSpace(string(stdout))
+	testCase.remediation.result = err
+	_, remediationMilliSeconds := duration(remediationStartTime, time.Now())
+	c.emitTask(item, events.TaskRemediate, "remediate", err, remediationMilliSeconds)
+
+	for _, name := range testCase.Before {
+		c.runTask(item, c.getIdByName(name), events.TaskBefore)
+	}
+
+	testCase.run(r)
+	if testCase.IsFailed() && testCase.Debug.Script != "" {
+		c.emitTask(item, events.TaskDebug, "debug", testCase.Debug.result, testCase.Debug.durationMilliSeconds)
+	}
+
+	for _, name := range testCase.After {
+		c.runTask(item, c.getIdByName(name), events.TaskAfter)
+	}
+
+	if testCase.IsSuccessful() {
+		testCase.remediation.state = RemediationFixed
+	} else {
+		testCase.remediation.state = RemediationStillFailing
+	}
+}
@@ -0,0 +1,403 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sbeliakou/check-up/modules/events"
+	"github.com/sbeliakou/check-up/modules/scoring"
+)
+
+// exitError is a script exit code
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e exitError) ExitCode() int {
+	return int(e)
+}
+
+// stubExecutor runs the scripts with the function, recording them along with their environment
+type stubExecutor struct {
+	mu      sync.Mutex
+	scripts []string
+	envs    [][]string
+	run     func(script string, env []string) (string, error)
+}
+
+func (e *stubExecutor) Run(script string, workdir string, timeout int, env []string, onLine func(string)) ([]byte, error) {
+	e.mu.Lock()
+	e.scripts = append(e.scripts, strings.TrimSpace(script))
+	e.envs = append(e.envs, env)
+	e.mu.Unlock()
+
+	if e.run == nil {
+		return nil, nil
+	}
+	stdout, err := e.run(strings.TrimSpace(script), env)
+	if onLine != nil {
+		for _, line := range strings.Split(stdout, "\n") {
+			onLine(line)
+		}
+	}
+	return []byte(stdout), err
+}
+
+// runSuite expands and runs the suite with the stub executor, the console output is returned
+func runSuite(t *testing.T, text string, opts Options) (*Suite, string) {
+	t.Helper()
+	var output bytes.Buffer
+	if opts.Output == nil {
+		opts.Output = &output
+	}
+	r := NewRunner(opts)
+	s := r.Expand(loadSuite(t, text))
+	r.Run([]*Suite{s})
+	return s, output.String()
+}
+
+func TestRemediation(t *testing.T) {
+	const text = `
+name: Remediation
+cases:
+- case: Remediable
+  script: |
+    check
+  remediate: |
+    fix
+- case: Not remediable
+  script: |
+    broken
+- case: Passing
+  script: |
+    true
+  remediate: |
+    fix
+`
+
+	tests := []struct {
+		name        string
+		opts        Options
+		fixes       bool
+		want        []string
+		wantScripts []string
+	}{
+		{
+			name:        "fixed",
+			fixes:       true,
+			want:        []string{RemediationFixed, RemediationNotRemediable, ""},
+			wantScripts: []string{"check", "fix", "check", "broken", "true"},
+		},
+		{
+			name:        "still failing",
+			want:        []string{RemediationStillFailing, RemediationNotRemediable, ""},
+			wantScripts: []string{"check", "fix", "check", "broken", "true"},
+		},
+		{
+			name:        "dry run",
+			opts:        Options{DryRun: true},
+			fixes:       true,
+			want:        []string{RemediationDryRun, RemediationNotRemediable, ""},
+			wantScripts: []string{"check", "broken", "true"},
+		},
+		{
+			name:        "declined",
+			opts:        Options{Interactive: true, Input: strings.NewReader("n\n")},
+			fixes:       true,
+			want:        []string{RemediationDeclined, RemediationNotRemediable, ""},
+			wantScripts: []string{"check", "broken", "true"},
+		},
+		{
+			name:        "confirmed",
+			opts:        Options{Interactive: true, Input: strings.NewReader("yes\n")},
+			fixes:       true,
+			want:        []string{RemediationFixed, RemediationNotRemediable, ""},
+			wantScripts: []string{"check", "fix", "check", "broken", "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed := false
+			executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+				switch {
+				case script == "fix":
+					fixed = tt.fixes
+					return "fixed", nil
+				case script == "broken" || (script == "check" && !fixed):
+					return "not ok", exitError(1)
+				}
+				return "ok", nil
+			}}
+
+			opts := tt.opts
+			opts.Remediate, opts.Executor = true, executor
+			s, _ := runSuite(t, text, opts)
+
+			for i, want := range tt.want {
+				if got := s.Cases[i].Result().RemediationStatus; got != want {
+					t.Errorf("case '%s': remediation = %q, want %q", s.Cases[i].Case, got, want)
+				}
+			}
+			if strings.Join(executor.scripts, ",") != strings.Join(tt.wantScripts, ",") {
+				t.Errorf("scripts = %v, want %v", executor.scripts, tt.wantScripts)
+			}
+		})
+	}
+}
+
+func TestStreamOutput(t *testing.T) {
+	const text = `
+name: Streaming
+cases:
+- name: prepare
+  script: |
+    prepare
+- case: Migration
+  before: [prepare]
+  env:
+    DB_PASSWORD: hunter22
+  script: |
+    migrate
+`
+
+	tests := []struct {
+		name   string
+		stream bool
+		want   []string
+	}{
+		{
+			name:   "streamed",
+			stream: true,
+			want:   []string{"[prepare] preparing\n", "[Migration] step 1\n", "[Migration] login *** ok\n"},
+		},
+		{
+			name: "not streamed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+				if script == "prepare" {
+					return "preparing", nil
+				}
+				return "step 1\nlogin hunter22 ok", nil
+			}}
+
+			_, output := runSuite(t, text, Options{Executor: executor, Stream: tt.stream})
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output doesn't contain %q:\n%s", want, output)
+				}
+			}
+			if !tt.stream && strings.Contains(output, "[Migration]") {
+				t.Errorf("output contains streamed lines:\n%s", output)
+			}
+			if strings.Contains(output, "hunter22") {
+				t.Errorf("output contains the secret:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	const text = `
+name: Events
+cases:
+- name: prepare
+  script: |
+    true
+- case: Passing
+  before: [prepare]
+  script: |
+    true
+- case: Failing
+  script: |
+    broken
+- case: Skipped
+  skip: true
+  script: |
+    true
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		if script == "broken" {
+			return "not ok", exitError(2)
+		}
+		return "ok", nil
+	}}
+
+	var stream bytes.Buffer
+	runSuite(t, text, Options{Executor: executor, Events: events.NewWriter(&stream)})
+
+	want := []string{
+		"suite_started  ",
+		"case_started Passing ",
+		"task_finished Passing passed",
+		"case_finished Passing passed",
+		"case_started Failing ",
+		"case_finished Failing failed",
+		"case_started Skipped ",
+		"case_finished Skipped skipped",
+		"suite_finished  ",
+	}
+
+	got := []string{}
+	exitCodes := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(stream.String()), "\n") {
+		var e events.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		if e.Suite != "Events" || e.Time.IsZero() {
+			t.Errorf("event suite = %q, time = %v, want the suite and time set", e.Suite, e.Time)
+		}
+		if e.Type == events.CaseFinished && e.ExitCode != nil {
+			exitCodes[e.Case] = *e.ExitCode
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", e.Type, e.Case, e.Status))
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if exitCodes["Passing"] != 0 || exitCodes["Failing"] != 2 {
+		t.Errorf("exit codes = %v, want Passing: 0, Failing: 2", exitCodes)
+	}
+}
+
+func TestRun(t *testing.T) {
+	const text = `
+name: Run
+env:
+  REGION: eu
+cases:
+- name: prepare
+  script: |
+    prepare
+- name: cleanup
+  script: |
+    cleanup
+- case: Passing
+  before: [prepare]
+  after: [cleanup]
+  env:
+    PORT: "80"
+  script: |
+    passing
+- case: Failing
+  weight: 3
+  script: |
+    failing
+- case: Skipped
+  skip: true
+  script: |
+    skipped
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		if script == "failing" {
+			return "not ok", exitError(1)
+		}
+		return "ok", nil
+	}}
+	s, _ := runSuite(t, text, Options{Executor: executor, EnvPolicy: &EnvPolicy{Mode: EnvIsolated}})
+
+	if got, want := strings.Join(executor.scripts, ","), "prepare,passing,cleanup,failing"; got != want {
+		t.Errorf("scripts = %s, want %s", got, want)
+	}
+	if env := strings.Join(executor.envs[1], " "); env != "PORT=80 REGION=eu" {
+		t.Errorf("env = %s, want PORT=80 REGION=eu", env)
+	}
+
+	summary := s.Summary()
+	if summary.Passed != 1 || summary.Failed != 1 || summary.Skipped != 1 || summary.Rating != 25 {
+		t.Errorf("summary = %+v, want 1 passed, 1 failed, 1 skipped, rated as 25", summary)
+	}
+	if code := s.Cases[3].Result().ExitCode; code != 1 {
+		t.Errorf("exit code of the failing case = %d, want 1", code)
+	}
+}
+
+func TestNativeChecksWorkdir(t *testing.T) {
+	const text = `
+name: Native checks
+cases:
+- case: Config exists
+  file:
+    path: app.conf
+- case: Config listens on 8080
+  file_content:
+    path: app.conf
+    contains: listen 8080
+`
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("listen 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		workdir string
+		want    string
+	}{
+		{"runner workdir", dir, scoring.StatusPassed},
+		{"other workdir", t.TempDir(), scoring.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := runSuite(t, text, Options{Workdir: tt.workdir})
+			for _, c := range s.Cases {
+				if got := c.Result().Status; got != tt.want {
+					t.Errorf("case '%s': status = %s, want %s, output: %s", c.Case, got, tt.want, c.Result().Stdout)
+				}
+			}
+		})
+	}
+}
+
+// secretStore is a store of the fixed secrets
+type secretStore map[string]string
+
+func (s secretStore) Secret(name string) (string, error) {
+	return s[name], nil
+}
+
+func TestSecretMasking(t *testing.T) {
+	const text = `
+name: Secrets
+secrets: [PIN]
+cases:
+- case: Login
+  env:
+    PIN: "4821"
+    PGPASSWORD: "{SECRET:db}"
+    MAX_TOKENS: "4096"
+  script: |
+    login
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		return "pin 4821, password 77, max tokens 4096", exitError(1)
+	}}
+	s, output := runSuite(t, text, Options{Executor: executor, Verbosity: 3, Secrets: secretStore{"db": "77"}})
+
+	want := "pin ***, password ***, max tokens 4096"
+	if !strings.Contains(output, want) {
+		t.Errorf("output doesn't contain %q:\n%s", want, output)
+	}
+	if stdout := s.Report().Tests[0].Stdout; stdout != want {
+		t.Errorf("report stdout = %q, want %q", stdout, want)
+	}
+}
@@ -0,0 +1,696 @@
+// Package checkup loads test suites and runs their cases, check-up command is a thin CLI over it:
+//
+//	suite, err := checkup.LoadSuiteFile("tests.yaml")
+//	...
+//	runner := checkup.NewRunner(checkup.Options{Verbosity: 1})
+//	suites := []*checkup.Suite{runner.Expand(suite)}
+//	runner.Run(suites)
+//	data := runner.Report(suites).Marshal()
+package checkup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/sbeliakou/check-up/modules/checks"
+	"github.com/sbeliakou/check-up/modules/dotenv"
+	"github.com/sbeliakou/check-up/modules/expect"
+	"github.com/sbeliakou/check-up/modules/mask"
+	"github.com/sbeliakou/check-up/modules/notify"
+	"github.com/sbeliakou/check-up/modules/schedule"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/snapshot"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+type LoopConfig struct {
+	Items   []string `yaml:"items"`
+	Command string   `yaml:"command"`
+}
+
+// Suite is a test suite as it's defined in YAML file, and its results after the run
+type Suite struct {
+	Name        string            `yaml:"name"`
+	FileName    string            `yaml:"filename"`
+	Cases       []Case            `yaml:"cases"`
+	CustomIndex string            `yaml:"custom_index"`
+	Env         map[string]string `yaml:"env"`
+	EnvFiles    []dotenv.File     `yaml:"envFiles"`
+	Secrets     []string          `yaml:"secrets"`
+	EnvPolicy   *EnvPolicy        `yaml:"env_policy"`
+	Category    string            `yaml:"category"`
+	SkippedAs   string            `yaml:"skipped_as"`
+	Schedule    string            `yaml:"schedule"`
+	Notify      []notify.Sink     `yaml:"notify"`
+
+	startTime time.Time
+	endTime   time.Time
+
+	all                  int
+	successfull          int
+	skipped              int
+	failed               int
+	waived               int
+	score                float64
+	summary              scoring.Summary
+	durationString       string
+	durationMilliSeconds int
+
+	ids    map[int]string
+	runner *Runner
+}
+
+// Case is a test case, or a task (before/after) when it has 'name' and no 'case' title
+type Case struct {
+	// YAML-Defined data
+	Name        string            `yaml:"name"`
+	Case        string            `yaml:"case"`
+	Env         map[string]string `yaml:"env"`
+	EnvFiles    []dotenv.File     `yaml:"envFiles"`
+	Secrets     []string          `yaml:"secrets"`
+	EnvPolicy   *EnvPolicy        `yaml:"env_policy"`
+	Workdir     string            `yaml:"workdir"`
+	Description string            `yaml:"description"`
+	Script      string            `yaml:"script"`
+	Shell       string            `yaml:"shell"`
+	Interpreter string            `yaml:"interpreter"`
+	Skip        bool              `yaml:"skip"`
+	Output      bool              `yaml:"output"`
+	Weight      int               `yaml:"weight"`
+	Log         string            `yaml:"log"`
+	Fatal       bool              `yaml:"fatal"`
+	Before      []string          `yaml:"before"`
+	After       []string          `yaml:"after"`
+	Loop        LoopConfig        `yaml:"loop"`
+	Timeout     int               `yaml:"timeout"`
+	Tags        []string          `yaml:"tags"`
+	Category    string            `yaml:"category"`
+	Severity    string            `yaml:"severity"`
+
+	// Native checks, an alternative to the script
+	checks.Checks `yaml:",inline"`
+
+	// Assertions on JSON or YAML output of the script
+	Expect expect.Expect `yaml:"expect"`
+
+	// Snapshot compares the output with the one recorded by the first run, after the normalizers are applied
+	Snapshot  bool                  `yaml:"snapshot"`
+	Normalize []snapshot.Normalizer `yaml:"normalize"`
+
+	// Compliance metadata
+	ControlID   string   `yaml:"control_id"`
+	Level       string   `yaml:"level"`
+	Rationale   string   `yaml:"rationale"`
+	Audit       string   `yaml:"audit"`
+	Remediation string   `yaml:"remediation"`
+	References  []string `yaml:"references"`
+
+	// Remediation script, runs in 'checkup fix' mode only when the case fails
+	Remediate string `yaml:"remediate"`
+
+	Debug struct {
+		Script  string `yaml:"script"`
+		Timeout int    `yaml:"timeout"`
+		stdout  string
+		result  error
+
+		durationMilliSeconds int
+	} `yaml:"debug"`
+
+	// Runtime data
+	status               string
+	result               error
+	stdout               string
+	durationString       string
+	durationMilliSeconds int
+
+	canShow bool
+	canRun  bool
+
+	title       string
+	item        string
+	loopPending bool
+
+	env         []string
+	declaredEnv []string
+	suiteEnv    map[string]string
+	envError    error
+
+	skipReason string
+
+	expectations   []expect.Result
+	snapshotFile   string
+	snapshotResult *snapshot.Result
+
+	waiver *waivers.Waiver
+
+	remediation struct {
+		state  string
+		stdout string
+		result error
+	}
+
+	errors []error
+}
+
+// empty tells that the case has neither script nor native checks to run
+func (s *Case) empty() bool {
+	return s.Script == "" && !s.Checks.Defined()
+}
+
+func (s *Case) IsSuccessful() bool {
+	return s.status == "success"
+}
+
+func (s *Case) IsFailed() bool {
+	return s.status == "failed"
+}
+
+func (s *Case) IsWaived() bool {
+	return s.status == "waived"
+}
+
+// state is the case status in terms of scoring: passed, failed, skipped or waived
+func (s *Case) state() string {
+	switch {
+	case s.Skip:
+		return scoring.StatusSkipped
+	case s.IsSuccessful():
+		return scoring.StatusPassed
+	case s.IsWaived():
+		return scoring.StatusWaived
+	}
+	return scoring.StatusFailed
+}
+
+func (s *Case) CanShow() bool {
+	// return s.Case != ""
+	return s.canShow
+}
+
+// Result is the outcome of the case execution
+type Result struct {
+	Status            string
+	Item              string
+	Stdout            string
+	ExitCode          int
+	SkipReason        string
+	RemediationStatus string
+	Waiver            *waivers.Waiver
+	Duration          time.Duration
+}
+
+// Result returns the outcome of the case after the run
+func (s *Case) Result() Result {
+	return Result{
+		Status:            s.state(),
+		Item:              s.item,
+		Stdout:            s.stdout,
+		ExitCode:          exitCode(s.result),
+		SkipReason:        s.skipReason,
+		RemediationStatus: s.remediation.state,
+		Waiver:            s.waiver,
+		Duration:          time.Duration(s.durationMilliSeconds) * time.Millisecond,
+	}
+}
+
+// Summary is the scores of the suite after the run
+func (c *Suite) Summary() scoring.Summary {
+	return c.summary
+}
+
+func (c *Suite) getScenarioIds() []int {
+	result := []int{}
+
+	for i := 0; i < len(c.Cases); i++ {
+		// if c.Cases[i].Skip {
+		// 	continue
+		// }
+
+		if c.Cases[i].canShow || c.Cases[i].canRun {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func (c *Suite) getScenarioCount() int {
+	result := 0
+	for _, i := range c.getScenarioIds() {
+		if c.Cases[i].CanShow() {
+			result++
+		}
+	}
+
+	return result
+}
+
+func (c *Suite) getIdByName(name string) int {
+	for id, item := range c.Cases {
+		if item.Name == name {
+			return id
+		}
+	}
+	return -1
+}
+
+func (c *Suite) skippedAs() string {
+	if c.runner != nil && c.runner.SkippedAs != "" {
+		return c.runner.SkippedAs
+	}
+	if c.SkippedAs != "" {
+		return c.SkippedAs
+	}
+	return scoring.SkippedExclude
+}
+
+func (c *Suite) scoringResults() []scoring.Result {
+	results := []scoring.Result{}
+
+	for _, i := range c.getScenarioIds() {
+		item := c.Cases[i]
+		if !item.CanShow() {
+			continue
+		}
+
+		results = append(results, scoring.Result{
+			Category:  item.Category,
+			Severity:  item.Severity,
+			Weight:    item.Weight,
+			Status:    item.state(),
+			SkippedAs: c.skippedAs(),
+			Unscored:  item.waiver != nil && item.waiver.Unscored,
+		})
+	}
+
+	return results
+}
+
+func (c *Suite) signOff() {
+	c.endTime = time.Now()
+
+	c.summary = scoring.Calculate(c.scoringResults())
+
+	c.successfull = c.summary.Passed
+	c.skipped = c.summary.Skipped
+	c.failed = c.summary.Failed
+	c.waived = c.summary.Waived
+	c.all = c.summary.All
+	c.score = c.summary.Rating
+	c.durationString, c.durationMilliSeconds = duration(c.startTime, c.endTime)
+}
+
+// caseIndex renders the index label of the case as it's shown in the console,
+// either using 'custom_index' template or the default "i/count" form
+func (c *Suite) caseIndex(id int, i int) string {
+	if c.CustomIndex != "" {
+		dataFuncMap := template.FuncMap{
+			"add": func(x, y int) int { return x + y },
+		}
+
+		data := map[string]interface{}{
+			"TaskId":    i - 1,
+			"TaskCount": c.getScenarioCount(),
+			"ControlID": c.Cases[id].ControlID,
+		}
+
+		tmpl, err := template.New("custom_index").Funcs(dataFuncMap).Parse(c.CustomIndex)
+		if err != nil {
+			panic(err)
+		}
+
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			panic(err)
+		}
+		return buf.String()
+	}
+
+	if c.Cases[id].Case != "" {
+		return fmt.Sprintf("%2d/%d", i, c.getScenarioCount())
+	}
+	return fmt.Sprintf("%2s/%s", "-", "-")
+}
+
+// matchControl checks the control id against comma separated list of glob patterns, e.g. "1.1.*,2.2.1"
+func matchControl(controlID string, patterns string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		if matched, _ := path.Match(strings.TrimSpace(pattern), controlID); matched && controlID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTag checks if any of comma separated tags is set
+func hasTag(tags []string, wanted string) bool {
+	for _, w := range strings.Split(wanted, ",") {
+		for _, tag := range tags {
+			if tag == strings.TrimSpace(w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoadSuite reads and validates YAML definition of the suite
+func LoadSuite(r io.Reader) (*Suite, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Suite{}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("cannot recognize configuration structure: %v", err)
+	}
+
+	if err := scoring.ValidateSkipped(s.SkippedAs); err != nil {
+		return nil, err
+	}
+
+	if s.Schedule != "" {
+		if _, err := schedule.Parse(s.Schedule); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range s.Notify {
+		if err := s.Notify[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, testCase := range s.Cases {
+		if err := scoring.ValidateSeverity(testCase.Severity); err != nil {
+			return nil, fmt.Errorf("case '%s': %v", testCase.Case, err)
+		}
+
+		if testCase.Checks.Defined() {
+			if strings.TrimSpace(testCase.Script) != "" {
+				return nil, fmt.Errorf("case '%s': 'script' and native checks can't be used together", testCase.Case)
+			}
+			if err := testCase.Checks.Validate(); err != nil {
+				return nil, fmt.Errorf("case '%s': %v", testCase.Case, err)
+			}
+		}
+
+		if testCase.Expect.Defined() {
+			if testCase.Checks.Defined() {
+				return nil, fmt.Errorf("case '%s': 'expect' asserts on the script output, it can't be used with native checks", testCase.Case)
+			}
+			if err := testCase.Expect.Validate(); err != nil {
+				return nil, fmt.Errorf("case '%s': %v", testCase.Case, err)
+			}
+		}
+
+		if err := snapshot.Validate(testCase.Normalize); err != nil {
+			return nil, fmt.Errorf("case '%s': %v", testCase.Case, err)
+		}
+
+		// 'interpreter' is an alias of 'shell'
+		if testCase.Interpreter != "" {
+			if testCase.Shell != "" && testCase.Shell != testCase.Interpreter {
+				return nil, fmt.Errorf("case '%s': 'shell' and 'interpreter' settings are different, only one of them is expected", testCase.Case)
+			}
+			s.Cases[i].Shell = testCase.Interpreter
+		}
+	}
+
+	return s, nil
+}
+
+// LoadSuiteFile reads the suite from the file, FileName of the suite is set to the file path
+func LoadSuiteFile(fileName string) (*Suite, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := LoadSuite(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	s.FileName = fileName
+
+	return s, nil
+}
+
+// remote tells whether the suite is loaded from the url, e.g. with -C option
+func (c *Suite) remote() bool {
+	return strings.Contains(c.FileName, "://")
+}
+
+// Expand prepares the suite to be run: applies the runner filters, timeout and env policy,
+// passes suite environment to the cases and expands loops into separate cases
+func (r *Runner) Expand(t *Suite) *Suite {
+	wdir, _ := os.Getwd()
+	if r.Workdir != "" {
+		wdir = r.Workdir
+	}
+
+	// suite environment is the values of env files overridden by env, env files refer to the process
+	// variables the env policy of the case passes; env files errors fail the cases when they run
+	type suiteEnv struct {
+		values map[string]string
+		err    error
+	}
+	suiteEnvs := map[string]suiteEnv{}
+	loadSuiteEnv := func(policy *EnvPolicy) suiteEnv {
+		if loaded, ok := suiteEnvs[policy.String()]; ok {
+			return loaded
+		}
+
+		inherited := policy.inherited()
+		values, err := dotenv.Load(t.EnvFiles, func(key string) (string, bool) {
+			if value, ok := t.Env[key]; ok {
+				return value, true
+			}
+			value, ok := inherited[key]
+			return value, ok
+		})
+		if values == nil {
+			values = map[string]string{}
+		}
+		for key, value := range t.Env {
+			values[key] = value
+		}
+
+		suiteEnvs[policy.String()] = suiteEnv{values, err}
+		return suiteEnvs[policy.String()]
+	}
+
+	a := &Suite{
+		Name:        t.Name,
+		FileName:    t.FileName,
+		CustomIndex: t.CustomIndex,
+		Category:    t.Category,
+		SkippedAs:   t.SkippedAs,
+		Schedule:    t.Schedule,
+		Notify:      t.Notify,
+		Cases:       []Case{},
+		runner:      r,
+	}
+
+	for i := 0; i < len(t.Cases); i++ {
+		if t.Cases[i].EnvPolicy == nil {
+			t.Cases[i].EnvPolicy = t.EnvPolicy
+		}
+		if r.EnvPolicy != nil {
+			t.Cases[i].EnvPolicy = r.EnvPolicy
+		}
+
+		loaded := loadSuiteEnv(t.Cases[i].EnvPolicy)
+		t.Cases[i].suiteEnv, t.Cases[i].envError = loaded.values, loaded.err
+
+		for _, key := range t.Secrets {
+			declared := false
+			for _, v := range t.Cases[i].Secrets {
+				declared = declared || v == key
+			}
+			if !declared {
+				t.Cases[i].Secrets = append(t.Cases[i].Secrets, key)
+			}
+		}
+
+		if t.Cases[i].Workdir != "" {
+			wdir = t.Cases[i].Workdir
+		} else {
+			t.Cases[i].Workdir = wdir
+			if wdir == "" {
+				wdir, _ = os.Getwd()
+			}
+		}
+
+		if t.Cases[i].Case != "" && strings.Contains(t.Cases[i].Case, r.Filter) {
+			t.Cases[i].canShow = true
+			t.Cases[i].canRun = true
+		}
+
+		if r.Controls != "" && t.Cases[i].Case != "" && !matchControl(t.Cases[i].ControlID, r.Controls) {
+			t.Cases[i].canShow = false
+			t.Cases[i].canRun = false
+		}
+
+		if r.Tags != "" && t.Cases[i].Case != "" && !hasTag(t.Cases[i].Tags, r.Tags) {
+			t.Cases[i].canShow = false
+			t.Cases[i].canRun = false
+		}
+
+		if t.Cases[i].Name == "" {
+			t.Cases[i].canRun = true
+		}
+
+		if t.Cases[i].CanShow() {
+			if t.Cases[i].Weight == 0 {
+				t.Cases[i].Weight = 1
+			}
+		}
+
+		t.Cases[i].title = t.Cases[i].Case
+
+		if t.Cases[i].Category == "" {
+			t.Cases[i].Category = t.Category
+		}
+
+		if r.Timeout > 0 {
+			t.Cases[i].Timeout = r.Timeout
+			t.Cases[i].Debug.Timeout = r.Timeout
+		}
+
+		if len(t.Cases[i].Loop.Items) > 0 || len(t.Cases[i].Loop.Command) > 0 {
+			Items := []string{}
+
+			if len(t.Cases[i].Loop.Items) > 0 {
+				Items = t.Cases[i].Loop.Items
+			}
+
+			if len(t.Cases[i].Loop.Command) > 0 && !r.SkipLoopCommand {
+				s := t.Cases[i]
+				s.Script = s.Loop.Command
+				s.Expect = expect.Expect{}
+				stdout, _ := s.run(r)
+
+				for _, item := range strings.Split(string(stdout), "\n") {
+					if item != "" {
+						Items = append(Items, item)
+					}
+				}
+			}
+
+			for _, item := range Items {
+				last := len(a.Cases)
+				a.Cases = append(a.Cases, t.Cases[i])
+
+				nameHasItemVar := regexp.MustCompile(`\$\{?item\}?`)
+				if len(nameHasItemVar.FindStringSubmatch(t.Cases[i].Case)) > 0 {
+					a.Cases[last].Case = nameHasItemVar.ReplaceAllString(t.Cases[i].Case, item)
+				} else {
+					a.Cases[last].Case = fmt.Sprintf("%s, item => \"%s\"", t.Cases[i].Case, item)
+				}
+
+				a.Cases[last].Env = make(map[string]string)
+				for k, v := range t.Cases[i].Env {
+					a.Cases[last].Env[k] = v
+				}
+
+				a.Cases[last].Env["item"] = item
+				a.Cases[last].item = item
+			}
+
+			if len(t.Cases[i].Loop.Command) > 0 && r.SkipLoopCommand {
+				// keeping the case as is, loop items are unknown until the command runs
+				a.Cases = append(a.Cases, t.Cases[i])
+				a.Cases[len(a.Cases)-1].loopPending = true
+			}
+
+		} else {
+			a.Cases = append(a.Cases, t.Cases[i])
+		}
+
+	}
+
+	return a
+}
+
+func duration(start time.Time, finish time.Time) (string, int) {
+	result := finish.Sub(start).Truncate(time.Millisecond)
+	resultInMilliSeconds := int(result.Milliseconds())
+	return result.String(), resultInMilliSeconds
+}
+
+// Item describes a case as it's shown by the list command
+type Item struct {
+	File    string            `json:"file"`
+	Suite   string            `json:"suite"`
+	Index   string            `json:"index"`
+	Control string            `json:"controlId,omitempty"`
+	Case    string            `json:"case"`
+	Item    string            `json:"item,omitempty"`
+	Loop    string            `json:"loopCommand,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Weight  int               `json:"weight"`
+	Skip    bool              `json:"skip,omitempty"`
+	Before  []string          `json:"before,omitempty"`
+	After   []string          `json:"after,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+// Items lists the cases of the suite, with their before/after tasks and environment if withTasks is set
+func (c *Suite) Items(withTasks bool) []Item {
+	result := []Item{}
+
+	j := 0
+	for _, id := range c.getScenarioIds() {
+		testCase := c.Cases[id]
+		if !testCase.CanShow() {
+			continue
+		}
+		j++
+
+		item := Item{
+			File:    c.FileName,
+			Suite:   c.Name,
+			Index:   strings.TrimSpace(c.caseIndex(id, j)),
+			Control: testCase.ControlID,
+			Case:    testCase.Case,
+			Item:    testCase.item,
+			Tags:    testCase.Tags,
+			Weight:  testCase.Weight,
+			Skip:    testCase.Skip || testCase.empty(),
+		}
+
+		if testCase.loopPending {
+			item.Loop = strings.TrimSpace(testCase.Loop.Command)
+		}
+
+		if withTasks {
+			item.Before = testCase.Before
+			item.After = testCase.After
+			item.Env = map[string]string{}
+			for key, value := range testCase.declared() {
+				if mask.Secret(key, testCase.Secrets) && value != "" {
+					value = mask.Mask
+				}
+				item.Env[key] = value
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
@@ -0,0 +1,275 @@
+package checkup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadSuite reads the suite from YAML text, failing the test when it's invalid
+func loadSuite(t *testing.T, text string) *Suite {
+	t.Helper()
+	s, err := LoadSuite(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// shownCases are the titles of the cases the expanded suite shows
+func shownCases(s *Suite) []string {
+	result := []string{}
+	for _, id := range s.getScenarioIds() {
+		if s.Cases[id].CanShow() {
+			result = append(result, s.Cases[id].Case)
+		}
+	}
+	return result
+}
+
+func TestMatchControl(t *testing.T) {
+	tests := []struct {
+		controlID string
+		patterns  string
+		want      bool
+	}{
+		{"1.1.2", "1.1.2", true},
+		{"1.1.2", "1.1.*", true},
+		{"1.1.2", "2.*, 1.1.*", true},
+		{"1.10", "1.1", false},
+		{"1.1.2", "1.2.*", false},
+		{"", "*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.controlID+" "+tt.patterns, func(t *testing.T) {
+			if got := matchControl(tt.controlID, tt.patterns); got != tt.want {
+				t.Errorf("matchControl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandControls(t *testing.T) {
+	const text = `
+name: Benchmark
+cases:
+- case: Ensure /tmp is a separate partition
+  control_id: 1.1.2
+  level: "1"
+  script: |
+    true
+- case: Ensure nodev option set on /tmp
+  control_id: 1.1.3
+  script: |
+    true
+- case: Ensure permissions on /etc/passwd
+  control_id: 6.1.2
+  script: |
+    true
+- case: No control id
+  script: |
+    true
+`
+
+	tests := []struct {
+		controls string
+		want     []string
+	}{
+		{"", []string{"Ensure /tmp is a separate partition", "Ensure nodev option set on /tmp", "Ensure permissions on /etc/passwd", "No control id"}},
+		{"1.1.*", []string{"Ensure /tmp is a separate partition", "Ensure nodev option set on /tmp"}},
+		{"1.1.3,6.*", []string{"Ensure nodev option set on /tmp", "Ensure permissions on /etc/passwd"}},
+		{"9.*", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.controls, func(t *testing.T) {
+			r := NewRunner(Options{Controls: tt.controls, Output: &bytes.Buffer{}})
+			got := shownCases(r.Expand(loadSuite(t, text)))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("cases = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSuiteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"not yaml", "cases: [\n", "cannot recognize configuration structure"},
+		{"skipped as", "skipped_as: ignore\n", "ignore"},
+		{"schedule", "schedule: every day\n", "every day"},
+		{"severity", "cases:\n- case: A\n  severity: urgent\n", "case 'A'"},
+		{"script and checks", "cases:\n- case: A\n  script: true\n  file:\n    path: /etc/hosts\n", "'script' and native checks can't be used together"},
+		{"expect and checks", "cases:\n- case: A\n  file:\n    path: /etc/hosts\n  expect:\n    json:\n    - query: .a\n      equals: 1\n", "it can't be used with native checks"},
+		{"shell and interpreter", "cases:\n- case: A\n  shell: python3\n  interpreter: pwsh\n  script: true\n", "'shell' and 'interpreter' settings are different"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSuite(strings.NewReader(tt.text))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadSuite() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSuiteFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "web.yaml")
+	if err := os.WriteFile(fileName, []byte("name: Web\ncases:\n- case: A\n  interpreter: python3\n  script: pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSuiteFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FileName != fileName || s.Cases[0].Shell != "python3" {
+		t.Errorf("file = %s, shell = %s, want %s, python3", s.FileName, s.Cases[0].Shell, fileName)
+	}
+
+	if _, err := LoadSuiteFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadSuiteFile() error = nil, want an error for the missing file")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	const text = `
+name: Web server
+category: web
+cases:
+- name: prepare
+  script: |
+    true
+- case: Port is open
+  tags: [network]
+  script: |
+    true
+- case: Config is valid
+  weight: 3
+  category: config
+  timeout: 5
+  script: |
+    true
+- case: Service $item is running
+  tags: [service]
+  loop:
+    items: [nginx, php-fpm]
+  script: |
+    true
+- case: Site is up
+  loop:
+    items: [example.com]
+  script: |
+    true
+`
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "all cases",
+			want: []string{
+				"Port is open", "Config is valid", "Service nginx is running", "Service php-fpm is running",
+				`Site is up, item => "example.com"`,
+			},
+		},
+		{
+			name: "filter",
+			opts: Options{Filter: "Service"},
+			want: []string{"Service nginx is running", "Service php-fpm is running"},
+		},
+		{
+			name: "tags",
+			opts: Options{Tags: "network, service"},
+			want: []string{"Port is open", "Service nginx is running", "Service php-fpm is running"},
+		},
+		{
+			name: "no matches",
+			opts: Options{Filter: "Database"},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Output = &bytes.Buffer{}
+			got := shownCases(NewRunner(tt.opts).Expand(loadSuite(t, text)))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("cases = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	s := NewRunner(Options{Timeout: 10, Output: &bytes.Buffer{}}).Expand(loadSuite(t, text))
+	settings := []struct {
+		id       int
+		weight   int
+		category string
+		item     string
+	}{
+		{1, 1, "web", ""},
+		{2, 3, "config", ""},
+		{3, 1, "web", "nginx"},
+		{4, 1, "web", "php-fpm"},
+	}
+	for _, want := range settings {
+		c := s.Cases[want.id]
+		if c.Weight != want.weight || c.Category != want.category || c.item != want.item || c.Timeout != 10 {
+			t.Errorf("case '%s': weight = %d, category = %s, item = %s, timeout = %d, want %d, %s, %s, 10",
+				c.Case, c.Weight, c.Category, c.item, c.Timeout, want.weight, want.category, want.item)
+		}
+	}
+	if item := s.Cases[3].Env["item"]; item != "nginx" {
+		t.Errorf("item env = %q, want nginx", item)
+	}
+}
+
+func TestExpandLoopCommand(t *testing.T) {
+	const text = `
+name: Loops
+cases:
+- case: User $item exists
+  loop:
+    items: [root]
+    command: list-users
+  script: |
+    true
+`
+
+	executor := &stubExecutor{run: func(script string, env []string) (string, error) {
+		return "alice\nbob\n", nil
+	}}
+
+	tests := []struct {
+		name     string
+		skip     bool
+		want     []string
+		wantRuns int
+	}{
+		{"evaluated", false, []string{"User root exists", "User alice exists", "User bob exists"}, 1},
+		{"skipped", true, []string{"User root exists", "User $item exists"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor.scripts = nil
+			r := NewRunner(Options{Executor: executor, SkipLoopCommand: tt.skip, Output: &bytes.Buffer{}})
+			got := shownCases(r.Expand(loadSuite(t, text)))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("cases = %q, want %q", got, tt.want)
+			}
+			if len(executor.scripts) != tt.wantRuns {
+				t.Errorf("scripts = %v, want %d runs of the loop command", executor.scripts, tt.wantRuns)
+			}
+		})
+	}
+}
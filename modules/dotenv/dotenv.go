@@ -0,0 +1,283 @@
+// Package dotenv reads environment files of suites and cases:
+//
+//	# comment
+//	export REGION=eu-central-1          # inline comment
+//	URL="https://${HOST:-localhost}:8080"
+//	GREETING='no $interpolation here'
+//	CERT="-----BEGIN CERTIFICATE-----
+//	...
+//	-----END CERTIFICATE-----"
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// File is an item of 'envFiles' setting, either a path or {path, optional}, missing optional files are skipped
+type File struct {
+	Path     string `yaml:"path"`
+	Optional bool   `yaml:"optional"`
+}
+
+// UnmarshalYAML accepts both the path string and the object
+func (f *File) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		*f = File{Path: path}
+		return nil
+	}
+
+	type file File
+	if err := unmarshal((*file)(f)); err != nil {
+		return err
+	}
+	if f.Path == "" {
+		return fmt.Errorf("envFiles: 'path' is required")
+	}
+	return nil
+}
+
+// Error points to the line of the file which can't be parsed
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Load reads the files one by one, later files override the values of the former ones and can refer to them;
+// lookup resolves the rest of references, e.g. os.LookupEnv
+func Load(files []File, lookup func(string) (string, bool)) (map[string]string, error) {
+	result := map[string]string{}
+	resolve := func(key string) (string, bool) {
+		if value, ok := result[key]; ok {
+			return value, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file.Path)
+		if err != nil {
+			if file.Optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("env file: %v", err)
+		}
+
+		values, err := Parse(f, file.Path, resolve)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			result[key] = value
+		}
+	}
+
+	return result, nil
+}
+
+// Parse reads the variables, name of the source is used in error messages, lookup resolves references
+// to the variables which aren't defined above in the same source
+func Parse(r io.Reader, name string, lookup func(string) (string, bool)) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	result := map[string]string{}
+	resolve := func(key string) (string, bool) {
+		if value, ok := result[key]; ok {
+			return value, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		fail := func(format string, args ...interface{}) error {
+			return &Error{File: name, Line: number, Message: fmt.Sprintf(format, args...)}
+		}
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest := strings.TrimPrefix(line, "export"); rest != line && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fail("expected KEY=VALUE, got '%s'", line)
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fail("invalid variable name '%s'", key)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			text := value[1:]
+
+			// the value continues on the next lines until the closing quote
+			end := closingQuote(text, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				text += "\n" + lines[i]
+				end = closingQuote(text, quote)
+			}
+			if end < 0 {
+				return nil, fail("unterminated quoted value of %s, the closing %c is missing", key, quote)
+			}
+
+			if rest := strings.TrimSpace(text[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fail("unexpected '%s' after the quoted value of %s", rest, key)
+			}
+
+			text = text[:end]
+			if quote == '"' {
+				text, err = expand(text, resolve, true)
+				if err != nil {
+					return nil, fail("%s: %v", key, err)
+				}
+			}
+			result[key] = text
+			continue
+		}
+
+		// inline comments of unquoted values start with ' #'
+		if index := strings.Index(value, " #"); index >= 0 {
+			value = value[:index]
+		}
+		if value, err = expand(strings.TrimSpace(value), resolve, false); err != nil {
+			return nil, fail("%s: %v", key, err)
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// closingQuote is the index of the unescaped quote, or -1
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// expand resolves $VAR, ${VAR}, ${VAR:-default} (when VAR is unset or empty) and ${VAR-default} (when VAR is unset),
+// defaults can refer to other variables; escapes of double-quoted values are replaced when they're on, '\$' is literal '$'
+func expand(text string, lookup func(string) (string, bool), escapes bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case escapes && text[i] == '\\' && i+1 < len(text):
+			i++
+			switch text[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(text[i])
+			}
+
+		case text[i] == '$' && i+1 < len(text) && text[i+1] == '{':
+			end := closingBrace(text, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("missing '}' in '%s'", text[i:])
+			}
+			value, err := substitute(text[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+
+		case text[i] == '$':
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || isAlnum(text[end])) {
+				end++
+			}
+			if end == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			value, _ := lookup(text[i+1 : end])
+			b.WriteString(value)
+			i = end - 1
+
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// substitute resolves the expression in braces
+func substitute(expression string, lookup func(string) (string, bool)) (string, error) {
+	name, fallback, hasDefault, emptyIsUnset := expression, "", false, false
+	if index := strings.Index(expression, "-"); index > 0 {
+		name, fallback, hasDefault = expression[:index], expression[index+1:], true
+		if strings.HasSuffix(name, ":") {
+			name, emptyIsUnset = strings.TrimSuffix(name, ":"), true
+		}
+	}
+	if !keyPattern.MatchString(name) {
+		return "", fmt.Errorf("invalid variable reference '${%s}'", expression)
+	}
+
+	value, ok := lookup(name)
+	if hasDefault && (!ok || (emptyIsUnset && value == "")) {
+		return expand(fallback, lookup, false)
+	}
+	return value, nil
+}
+
+// closingBrace is the index of '}' matching the opening one before start, or -1
+func closingBrace(text string, start int) int {
+	depth := 1
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
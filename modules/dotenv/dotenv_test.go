@@ -0,0 +1,124 @@
+package dotenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func environment(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestParse(t *testing.T) {
+	env := environment(map[string]string{"HOST": "example.com", "EMPTY": ""})
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{"plain", "A=1\nB = two words \n", map[string]string{"A": "1", "B": "two words"}},
+		{"comments and blank lines", "# comment\n\n  # indented\nA=1 # inline\nB=a#b\n", map[string]string{"A": "1", "B": "a#b"}},
+		{"export", "export A=1\nexport\tB=2\nexported=3\n", map[string]string{"A": "1", "B": "2", "exported": "3"}},
+		{"empty value", "A=\nB=''\n", map[string]string{"A": "", "B": ""}},
+		{"dotted name", "app.port=8080\n", map[string]string{"app.port": "8080"}},
+		{"windows line endings", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"double quotes", `A="a # not a comment"  # comment`, map[string]string{"A": "a # not a comment"}},
+		{"single quotes", `A='$HOST \n "x"'`, map[string]string{"A": `$HOST \n "x"`}},
+		{"escapes", `A="line\nnext\ttab \"quoted\" \$HOST \\"`, map[string]string{"A": "line\nnext\ttab \"quoted\" $HOST \\"}},
+		{"no escapes unquoted", `A=a\nb`, map[string]string{"A": `a\nb`}},
+		{"multiline", "CERT=\"-----BEGIN-----\nabc\n-----END-----\"\nB=1\n", map[string]string{"CERT": "-----BEGIN-----\nabc\n-----END-----", "B": "1"}},
+		{"multiline single quotes", "A='first\n  second'\n", map[string]string{"A": "first\n  second"}},
+		{"interpolation", "URL=https://$HOST:${PORT:-8080}/\n", map[string]string{"URL": "https://example.com:8080/"}},
+		{"defined above", "PORT=9090\nURL=\"$HOST:${PORT:-8080}\"\n", map[string]string{"PORT": "9090", "URL": "example.com:9090"}},
+		{"empty default", "A=${EMPTY:-x}\nB=${EMPTY-x}\nC=${UNSET-x}\n", map[string]string{"A": "x", "B": "", "C": "x"}},
+		{"nested default", "A=${UNSET:-${HOST}}\n", map[string]string{"A": "example.com"}},
+		{"unknown variable", "A=[$UNSET]\n", map[string]string{"A": "[]"}},
+		{"literal dollar", "A=cost: 5$\nB=$ 1\n", map[string]string{"A": "cost: 5$", "B": "$ 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.content), "test.env", env)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int
+	}{
+		{"no assignment", "A=1\nB\n", 2},
+		{"invalid name", "1A=1\n", 1},
+		{"name with dash", "MY-VAR=1\n", 1},
+		{"unterminated quotes", "A=1\nB=\"abc\nC=2\n", 2},
+		{"text after quotes", "A='abc' def\n", 1},
+		{"missing brace", "A=${HOST\n", 1},
+		{"invalid reference", "A=${MY-HOST:-x}\nB=${1X}\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.content), "test.env", nil)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Parse() error = %v, want *Error", err)
+			}
+			if e.Line != tt.wantLine || e.File != "test.env" {
+				t.Errorf("Parse() error at %s:%d, want test.env:%d: %v", e.File, e.Line, tt.wantLine, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	common := write("common.env", "HOST=localhost\nPORT=80\n")
+	local := write("local.env", "PORT=8080\nURL=http://$HOST:$PORT/$USER_NAME\n")
+	missing := filepath.Join(dir, "missing.env")
+
+	tests := []struct {
+		name    string
+		files   []File
+		want    map[string]string
+		wantErr bool
+	}{
+		{"override and refer", []File{{Path: common}, {Path: local}}, map[string]string{"HOST": "localhost", "PORT": "8080", "URL": "http://localhost:8080/admin"}, false},
+		{"optional missing", []File{{Path: common}, {Path: missing, Optional: true}}, map[string]string{"HOST": "localhost", "PORT": "80"}, false},
+		{"required missing", []File{{Path: missing}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.files, environment(map[string]string{"USER_NAME": "admin", "PORT": "1"}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+)
+
+// Event types
+const (
+	SuiteStarted  = "suite_started"
+	CaseStarted   = "case_started"
+	TaskFinished  = "task_finished"
+	CaseFinished  = "case_finished"
+	SuiteFinished = "suite_finished"
+)
+
+// Kinds of tasks in task_finished events
+const (
+	TaskBefore    = "before"
+	TaskAfter     = "after"
+	TaskDebug     = "debug"
+	TaskRemediate = "remediate"
+)
+
+// Event is a single line of the event stream
+type Event struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Suite string    `json:"suite"`
+	File  string    `json:"file,omitempty"`
+
+	// suite_started
+	Tests int `json:"tests,omitempty"`
+
+	// case events
+	ID                   string `json:"id,omitempty"`
+	Index                string `json:"index,omitempty"`
+	Case                 string `json:"case,omitempty"`
+	Item                 string `json:"item,omitempty"`
+	Status               string `json:"status,omitempty"`
+	DurationMilliSeconds int    `json:"durationMilliSeconds,omitempty"`
+	ExitCode             *int   `json:"exitCode,omitempty"`
+
+	// task_finished
+	Kind string `json:"kind,omitempty"`
+	Task string `json:"task,omitempty"`
+
+	// suite_finished
+	Summary *report.Summary `json:"summary,omitempty"`
+}
+
+// Code makes the exit code field, which is omitted only when it's not set
+func Code(code int) *int {
+	return &code
+}
+
+// Writer encodes events as newline delimited JSON
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Writer{enc: enc}
+}
+
+// Emit writes the event, setting its time if it's not set yet
+func (w *Writer) Emit(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(e)
+}
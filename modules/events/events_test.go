@@ -0,0 +1,73 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmit(t *testing.T) {
+	at := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "suite started",
+			event: Event{Type: SuiteStarted, Time: at, Suite: "Web", File: "web.yaml", Tests: 2},
+			want:  `{"type":"suite_started","time":"2026-03-10T09:00:00Z","suite":"Web","file":"web.yaml","tests":2}`,
+		},
+		{
+			name:  "html is not escaped",
+			event: Event{Type: CaseStarted, Time: at, Suite: "Web", Case: "<title> & more"},
+			want:  `{"type":"case_started","time":"2026-03-10T09:00:00Z","suite":"Web","case":"<title> & more"}`,
+		},
+		{
+			name:  "zero exit code",
+			event: Event{Type: TaskFinished, Time: at, Suite: "Web", Kind: TaskBefore, Task: "prepare", ExitCode: Code(0)},
+			want:  `{"type":"task_finished","time":"2026-03-10T09:00:00Z","suite":"Web","exitCode":0,"kind":"before","task":"prepare"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := NewWriter(&output).Emit(tt.event); err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != tt.want+"\n" {
+				t.Errorf("Emit() = %s, want %s", output.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEmitTime(t *testing.T) {
+	var output bytes.Buffer
+	w := NewWriter(&output)
+
+	before := time.Now()
+	for _, event := range []Event{{Type: SuiteStarted}, {Type: SuiteFinished}} {
+		if err := w.Emit(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2: %s", len(lines), output.String())
+	}
+	for _, line := range lines {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Time.Before(before.Truncate(time.Second)) {
+			t.Errorf("time = %v, want the current time", event.Time)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package expect asserts on structured output of the case script: stdout is parsed as JSON or YAML
+// and the values found by jq-like queries are compared with the expected ones
+package expect
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Expect is the 'expect' setting of the case, assertions of the format are applied to the parsed stdout
+type Expect struct {
+	JSON []Assertion `yaml:"json"`
+	YAML []Assertion `yaml:"yaml"`
+}
+
+// Assertion is a query and one or more operators, every operator is checked separately
+type Assertion struct {
+	Query    string      `yaml:"query"`
+	Equals   interface{} `yaml:"equals"`
+	Contains interface{} `yaml:"contains"`
+	Gt       *float64    `yaml:"gt"`
+	Length   *int        `yaml:"length"`
+	Exists   *bool       `yaml:"exists"`
+}
+
+// Result of the single operator of the assertion
+type Result struct {
+	Format   string      `json:"format"`
+	Query    string      `json:"query"`
+	Operator string      `json:"operator"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+	Passed   bool        `json:"passed"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// String describes the result for the console and failure messages
+func (r Result) String() string {
+	if r.Error != "" {
+		return fmt.Sprintf("expect.%s %s: %s", r.Format, r.Query, r.Error)
+	}
+	if r.Passed {
+		return fmt.Sprintf("expect.%s %s: %s %s", r.Format, r.Query, r.Operator, format(r.Expected))
+	}
+	return fmt.Sprintf("expect.%s %s: expected %s %s, got %s", r.Format, r.Query, r.Operator, format(r.Expected), format(r.Actual))
+}
+
+// Defined tells whether the case has any assertions
+func (e Expect) Defined() bool {
+	return len(e.JSON) > 0 || len(e.YAML) > 0
+}
+
+// Validate checks the queries and that every assertion has an operator
+func (e Expect) Validate() error {
+	for i, assertions := range [][]Assertion{e.JSON, e.YAML} {
+		name := []string{"json", "yaml"}[i]
+		for _, a := range assertions {
+			if _, err := parse(a.Query); err != nil {
+				return fmt.Errorf("expect.%s: %v", name, err)
+			}
+			if a.Equals == nil && a.Contains == nil && a.Gt == nil && a.Length == nil && a.Exists == nil {
+				return fmt.Errorf("expect.%s %s: one of 'equals', 'contains', 'gt', 'length' or 'exists' is required", name, a.Query)
+			}
+		}
+	}
+	return nil
+}
+
+// Check parses the output and evaluates the assertions, lookup resolves $VAR references in queries and
+// expected strings; the error is a Failure listing failed assertions
+func (e Expect) Check(stdout string, lookup func(string) string) ([]Result, error) {
+	results := []Result{}
+
+	if len(e.JSON) > 0 {
+		var doc interface{}
+		err := json.Unmarshal([]byte(stdout), &doc)
+		results = append(results, evaluate("json", doc, err, e.JSON, lookup)...)
+	}
+
+	if len(e.YAML) > 0 {
+		var doc interface{}
+		err := yaml.Unmarshal([]byte(stdout), &doc)
+		results = append(results, evaluate("yaml", normalize(doc), err, e.YAML, lookup)...)
+	}
+
+	failures := []string{}
+	for _, r := range results {
+		if !r.Passed {
+			failures = append(failures, r.String())
+		}
+	}
+	if len(failures) > 0 {
+		return results, &Failure{Message: strings.Join(failures, "\n")}
+	}
+	return results, nil
+}
+
+func evaluate(name string, doc interface{}, parseErr error, assertions []Assertion, lookup func(string) string) []Result {
+	if parseErr != nil {
+		return []Result{{Format: name, Query: ".", Operator: "parse", Error: fmt.Sprintf("stdout is not valid %s: %v", strings.ToUpper(name), parseErr)}}
+	}
+
+	results := []Result{}
+	for _, a := range assertions {
+		query, err := expandQuery(a.Query, lookup)
+		var steps []step
+		if err == nil {
+			steps, err = parse(query)
+		}
+		if err != nil {
+			results = append(results, Result{Format: name, Query: query, Operator: "query", Error: err.Error()})
+			continue
+		}
+		actual, found := resolve(doc, steps)
+
+		check := func(operator string, expected interface{}, compare func() (interface{}, bool)) {
+			r := Result{Format: name, Query: query, Operator: operator, Expected: expected, Actual: actual}
+			if !found && operator != "exists" {
+				r.Error = fmt.Sprintf("expected %s %s, got nothing: the path doesn't exist", operator, format(expected))
+			} else {
+				r.Actual, r.Passed = compare()
+			}
+			results = append(results, r)
+		}
+
+		if a.Exists != nil {
+			check("exists", *a.Exists, func() (interface{}, bool) {
+				return found, found == *a.Exists
+			})
+		}
+		if a.Equals != nil {
+			expected := normalize(expandStrings(a.Equals, lookup))
+			check("equals", expected, func() (interface{}, bool) {
+				return actual, reflect.DeepEqual(actual, expected)
+			})
+		}
+		if a.Contains != nil {
+			expected := normalize(expandStrings(a.Contains, lookup))
+			check("contains", expected, func() (interface{}, bool) {
+				return actual, contains(actual, expected)
+			})
+		}
+		if a.Gt != nil {
+			check("gt", *a.Gt, func() (interface{}, bool) {
+				n, ok := number(actual)
+				return actual, ok && n > *a.Gt
+			})
+		}
+		if a.Length != nil {
+			check("length", *a.Length, func() (interface{}, bool) {
+				n, ok := length(actual)
+				if !ok {
+					return actual, false
+				}
+				return n, n == *a.Length
+			})
+		}
+	}
+
+	return results
+}
+
+// expandQuery resolves variable references of the query, empty ones are errors and the query is returned as is: '.items.$KEY' would be
+// a different query without the key, e.g. '.' is the whole document
+func expandQuery(query string, lookup func(string) string) (string, error) {
+	var empty []string
+	expanded := os.Expand(query, func(key string) string {
+		value := lookup(key)
+		if value == "" {
+			empty = append(empty, "$"+key)
+		}
+		return value
+	})
+	if len(empty) > 0 {
+		return query, fmt.Errorf("%s is empty", strings.Join(empty, ", "))
+	}
+	return expanded, nil
+}
+
+// normalize converts YAML maps to JSON-like ones and numbers to float64, so that values parsed
+// from JSON and YAML can be compared with each other
+func normalize(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for k, item := range value {
+			result[fmt.Sprint(k)] = normalize(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for k, item := range value {
+			result[k] = normalize(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = normalize(item)
+		}
+		return result
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	}
+	return v
+}
+
+// expandStrings resolves variable references in the strings of the expected value
+func expandStrings(v interface{}, lookup func(string) string) interface{} {
+	switch value := v.(type) {
+	case string:
+		return os.Expand(value, lookup)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = expandStrings(item, lookup)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := map[interface{}]interface{}{}
+		for k, item := range value {
+			result[k] = expandStrings(item, lookup)
+		}
+		return result
+	}
+	return v
+}
+
+// contains is a substring of a string, an element of a list, or a key of an object
+func contains(actual interface{}, expected interface{}) bool {
+	switch value := actual.(type) {
+	case string:
+		if s, ok := expected.(string); ok {
+			return strings.Contains(value, s)
+		}
+	case []interface{}:
+		for _, item := range value {
+			if reflect.DeepEqual(item, expected) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if s, ok := expected.(string); ok {
+			_, found := value[s]
+			return found
+		}
+	}
+	return false
+}
+
+// number accepts numbers and numeric strings, e.g. "42" in kubectl output
+func number(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// length of a list, an object or a string in characters
+func length(v interface{}) (int, bool) {
+	switch value := v.(type) {
+	case []interface{}:
+		return len(value), true
+	case map[string]interface{}:
+		return len(value), true
+	case string:
+		return utf8.RuneCountInString(value), true
+	}
+	return 0, false
+}
+
+// format shows the value as JSON, e.g. strings are quoted
+func format(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Failure is the result of failed assertions, it exits with 1 as a failed script would
+type Failure struct {
+	Message string
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+func (f *Failure) ExitCode() int {
+	return 1
+}
@@ -0,0 +1,87 @@
+package expect
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func assertions(t *testing.T, text string) Expect {
+	t.Helper()
+	var e Expect
+	if err := yaml.Unmarshal([]byte(text), &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		expect  string
+		wantErr bool
+	}{
+		{"valid", "json:\n- query: .status\n  equals: ok\n", false},
+		{"variable in query", "yaml:\n- query: .items.$KEY\n  exists: true\n", false},
+		{"no operator", "json:\n- query: .status\n", true},
+		{"invalid query", "json:\n- query: .items[\n  exists: true\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := assertions(t, tt.expect).Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	vars := map[string]string{"NAME": "web", "KEY": "replicas", "PATH_PART": "."}
+	lookup := func(name string) string {
+		return vars[name]
+	}
+	stdoutJSON := `{"name": "web", "replicas": 3, "ready": "2", "tags": ["a", "b"], "labels": {"tier": "front"}}`
+	stdoutYAML := "name: web\nreplicas: 3\ntags: [a, b]\n"
+
+	tests := []struct {
+		name       string
+		expect     string
+		stdout     string
+		wantPassed []bool
+	}{
+		{"equals", "json:\n- query: .name\n  equals: $NAME\n", stdoutJSON, []bool{true}},
+		{"equals number", "json:\n- query: .replicas\n  equals: 3\n", stdoutJSON, []bool{true}},
+		{"yaml equals number", "yaml:\n- query: .replicas\n  equals: 3\n", stdoutYAML, []bool{true}},
+		{"yaml equals list", "yaml:\n- query: .tags\n  equals: [a, b]\n", stdoutYAML, []bool{true}},
+		{"contains", "json:\n- query: .tags\n  contains: b\n  length: 3\n", stdoutJSON, []bool{true, false}},
+		{"contains key", "json:\n- query: .labels\n  contains: tier\n", stdoutJSON, []bool{true}},
+		{"gt numeric string", "json:\n- query: .ready\n  gt: 1\n", stdoutJSON, []bool{true}},
+		{"exists", "json:\n- query: .missing\n  exists: false\n- query: .name\n  exists: true\n", stdoutJSON, []bool{true, true}},
+		{"missing path", "json:\n- query: .missing\n  equals: 1\n", stdoutJSON, []bool{false}},
+		{"variable in query", "json:\n- query: .$KEY\n  equals: 3\n", stdoutJSON, []bool{true}},
+		{"empty variable in query", "json:\n- query: .items.$EMPTY\n  exists: false\n", stdoutJSON, []bool{false}},
+		{"invalid expanded query", "json:\n- query: .name$PATH_PART\n  exists: true\n", stdoutJSON, []bool{false}},
+		{"not JSON", "json:\n- query: .name\n  exists: true\n", "name: web", []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := assertions(t, tt.expect).Check(tt.stdout, lookup)
+			if len(results) != len(tt.wantPassed) {
+				t.Fatalf("Check() = %+v, want %d results", results, len(tt.wantPassed))
+			}
+
+			failed := false
+			for i, r := range results {
+				if r.Passed != tt.wantPassed[i] {
+					t.Errorf("result %d: %s, want passed %v", i, r, tt.wantPassed[i])
+				}
+				failed = failed || !r.Passed
+			}
+			if (err != nil) != failed {
+				t.Errorf("Check() error = %v, want an error %v", err, failed)
+			}
+		})
+	}
+}
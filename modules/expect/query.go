@@ -0,0 +1,142 @@
+package expect
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// step is a segment of the query: object key, array index or wildcard
+type step struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parse splits jq-like path into steps, e.g. '.items[0].metadata.name', '.items[*].status',
+// '.metadata.labels["app.kubernetes.io/name"]'; '.' is the whole document
+func parse(query string) ([]step, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("empty query")
+	}
+	if query[0] != '.' && query[0] != '[' {
+		query = "." + query
+	}
+
+	steps := []step{}
+	for i := 0; i < len(query); {
+		switch query[i] {
+		case '.':
+			i++
+			if i == len(query) && len(query) > 1 {
+				return nil, fmt.Errorf("query '%s': missing key after the trailing '.'", query)
+			}
+			if i == len(query) || query[i] == '[' {
+				continue
+			}
+			end := i
+			for end < len(query) && query[end] != '.' && query[end] != '[' {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("query '%s': empty key at %d", query, i)
+			}
+			if key := query[i:end]; key == "*" {
+				steps = append(steps, step{wildcard: true})
+			} else {
+				steps = append(steps, step{key: key})
+			}
+			i = end
+
+		case '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("query '%s': missing ']'", query)
+			}
+			inner := strings.TrimSpace(query[i+1 : i+end])
+			switch {
+			case inner == "*" || inner == "":
+				steps = append(steps, step{wildcard: true})
+			case len(inner) > 1 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, step{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("query '%s': '%s' is neither an index nor a quoted key", query, inner)
+				}
+				steps = append(steps, step{index: index, isIndex: true})
+			}
+			i += end + 1
+
+		default:
+			return nil, fmt.Errorf("query '%s': unexpected '%c' at %d", query, query[i], i)
+		}
+	}
+
+	return steps, nil
+}
+
+// resolve finds the steps in the document, wildcards collect the matches into a list,
+// found is false when the path doesn't exist
+func resolve(doc interface{}, steps []step) (interface{}, bool) {
+	if len(steps) == 0 {
+		return doc, true
+	}
+
+	s := steps[0]
+	switch {
+	case s.wildcard:
+		children := []interface{}{}
+		switch v := doc.(type) {
+		case []interface{}:
+			children = v
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				children = append(children, v[k])
+			}
+		default:
+			return nil, false
+		}
+
+		result := []interface{}{}
+		for _, child := range children {
+			if value, ok := resolve(child, steps[1:]); ok {
+				result = append(result, value)
+			}
+		}
+		return result, true
+
+	case s.isIndex:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return nil, false
+		}
+		index := s.index
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil, false
+		}
+		return resolve(list[index], steps[1:])
+
+	default:
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok := object[s.key]
+		if !ok {
+			return nil, false
+		}
+		return resolve(value, steps[1:])
+	}
+}
@@ -0,0 +1,98 @@
+package expect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []step
+	}{
+		{".", []step{}},
+		{"items", []step{{key: "items"}}},
+		{".items[0].metadata.name", []step{{key: "items"}, {index: 0, isIndex: true}, {key: "metadata"}, {key: "name"}}},
+		{".items[-1]", []step{{key: "items"}, {index: -1, isIndex: true}}},
+		{".items[*].status", []step{{key: "items"}, {wildcard: true}, {key: "status"}}},
+		{".items[].status", []step{{key: "items"}, {wildcard: true}, {key: "status"}}},
+		{".labels.*", []step{{key: "labels"}, {wildcard: true}}},
+		{`.labels["app.kubernetes.io/name"]`, []step{{key: "labels"}, {key: "app.kubernetes.io/name"}}},
+		{".labels['tier']", []step{{key: "labels"}, {key: "tier"}}},
+		{"[0]", []step{{index: 0, isIndex: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parse(tt.query)
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty", "  "},
+		{"trailing dot", ".items."},
+		{"empty key", ".items..name"},
+		{"missing bracket", ".items[0"},
+		{"not an index", ".items[first]"},
+		{"unbalanced quotes", `.labels["app]`},
+		{"unexpected character", `.items[0]name`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if steps, err := parse(tt.query); err == nil {
+				t.Errorf("parse(%q) = %+v, want an error", tt.query, steps)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	doc := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "ready": true},
+			map[string]interface{}{"name": "b"},
+		},
+		"labels": map[string]interface{}{"tier": "web", "app": "shop"},
+	}
+
+	tests := []struct {
+		query     string
+		want      interface{}
+		wantFound bool
+	}{
+		{".items[1].name", "b", true},
+		{".items[-1].name", "b", true},
+		{".items[2]", nil, false},
+		{".items[*].name", []interface{}{"a", "b"}, true},
+		{".items[*].ready", []interface{}{true}, true},
+		{".labels.*", []interface{}{"shop", "web"}, true},
+		{".labels.missing", nil, false},
+		{".labels[0]", nil, false},
+		{".items.name", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			steps, err := parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, found := resolve(doc, steps)
+			if found != tt.wantFound || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
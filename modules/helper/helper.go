@@ -68,6 +68,10 @@ func CustomUsage() {
   
     ./checkup -c filename|directory other options
     ./checkup -C url other options
+    ./checkup list -c filename|directory other options
+    ./checkup fix -c filename|directory other options
+    ./checkup history other options
+    ./checkup serve -c filename|directory --listen :8080
 
 Mandatory Options (One of them):
           
@@ -85,18 +89,134 @@ Other Options:
     -f <regexp>
           Run tests matching the specified regular expression for test names.
           
+    --tag <tags>
+          Run tests having any of comma separated tags.
+
+    --control <patterns>
+          Run tests which 'control_id' matches comma separated glob patterns, e.g. 1.1.*
+
     -o <format=filename>
-          Output the test results to a file. Supports JSON or JUnit formats.
+          Output the test results to a file. Supports JSON, JUnit or Prometheus formats.
+          Can be repeated to save several reports at once.
           
           Suppoerted formats:
           - json
           - junit
+          - prometheus (node_exporter textfile collector format)
           
     -w <directory>
           Set the working directory for the test execution context.
+
+    --waivers <filename>
+          Waivers file with accepted failures, waived cases aren't counted as failures.
+
+    --baseline <filename>
+          Previous JSON report to compare the results with: rating delta, newly failing,
+          newly passing, added and removed cases.
+
+    --fail-on-regression
+          Exit with non-zero code when cases which passed in the baseline fail.
+
+    --history
+          Append the run results to the local history file,
+          default: ~/.local/share/checkup/history.jsonl
+
+    --history-file <filename>
+          Local history file path, implies --history.
+
+    --reporter <default|dots|json|github-actions>
+          Console output format:
+          - default: a line per case, details depending on verbosity
+          - dots: a character per case, details of failed cases after the suite
+          - json: the event stream (see --events) on stdout, other messages go to stderr
+          - github-actions: default output with error annotations of failed cases
+
+    --color <auto|always|never>
+          Colored console output, NO_COLOR environment variable disables colors.
+          Default: auto, colors are used when stdout is a terminal
+
+    --events <filename|->
+          Stream run events as newline delimited JSON to the file, or to stdout with '-'.
+
+    --stream
+          Print script output line by line with the task name and timestamp while
+          the case is running, and show the running case with elapsed time on a terminal.
+
+    --notify <format=url>
+          Post the run summary to the url after the run, can be repeated.
+          Formats: webhook (JSON summary), slack, mattermost, teams.
+
+    --notify-on <conditions>
+          Comma separated conditions of --notify notifications: failure, change, always.
+          Default: always
+
+    --secrets <env[:PREFIX]|file:path|dir:path>
+          Store of {SECRET:name} references in env values:
+          - env: process environment variable PREFIX + name (default)
+          - file: dotenv file of name=value lines
+          - dir: pass-like directory, a file per secret (.gpg files are decrypted)
+
+    --require-env
+          Fail the cases which env values have unresolved references, instead of
+          running them with empty values.
+
+    --env-policy <inherit|isolated|allowlist:NAME,...>
+          Process environment passed to the scripts, overrides 'env_policy' setting:
+          - inherit: all of the variables (default)
+          - isolated: none of them, only the variables of the suite and the case
+          - allowlist: the listed names and glob patterns, e.g. allowlist:PATH,HOME,LC_*
+
+    --update-snapshots
+          Record the current output of 'snapshot: true' cases, replacing the snapshots
+          which don't match it.
+
+    --skipped-as <exclude|fail|pass>
+          Treatment of skipped cases in ratings, overrides 'skipped_as' suite setting.
+          Default: exclude
           
     --version
           Show current version
+
+Remediation Options (checkup fix):
+
+    --remediate
+          Run 'remediate' scripts of failed cases and check them again, the same as 'checkup fix'.
+
+    --dry-run
+          Show remediation scripts which would be executed, without running them.
+
+    --interactive
+          Ask for confirmation before running every remediation script.
+
+Serve Options (checkup serve):
+
+    --listen <address>
+          Address of HTTP API. Default: :8080
+
+    --results-dir <directory>
+          Directory for the results of scheduled runs. Default: ~/.local/share/checkup/results
+
+    --retain <number>
+          Number of the results of scheduled runs kept per suite. Default: 10
+
+History Options (checkup history):
+
+    --last <number>
+          Number of the latest runs to analyze. Default: 20
+
+    --top <number>
+          Number of the slowest cases to show. Default: 10
+
+List Options (checkup list):
+
+    --format <table|json|tree>
+          Output format of the cases list. Default: table
+
+    --tasks
+          Also show before/after tasks and environment of every case.
+
+    --eval-loop
+          Run 'loop.command' scripts to expand loop items. Nothing else is executed.
           
     -v, --verbosity
           Set the verbosity level to control the amount and type of output:
@@ -133,6 +253,9 @@ Examples:
   ./checkup -c tests.yaml -f user 
       Runs only those tasks which "case:" field contains word "user"
 
+  ./checkup list -c tests/ --format tree --tasks
+      Shows the cases which would run, without running them
+
 Additional Information:
   Complete documentation and more details are available at:
   https://github.com/sbeliakou/check-up/
@@ -0,0 +1,272 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+// Case is a single case result in the history
+type Case struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	State                string `json:"state"`
+	DurationMilliSeconds int    `json:"durationMilliSeconds"`
+}
+
+// Suite is a single suite result in the history
+type Suite struct {
+	Name                 string  `json:"name"`
+	File                 string  `json:"file,omitempty"`
+	Rating               float64 `json:"rating"`
+	DurationMilliSeconds int     `json:"durationMilliSeconds"`
+	Cases                []Case  `json:"cases"`
+}
+
+// Entry is a line of the history file, one per run
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname,omitempty"`
+	Rating   float64   `json:"rating"`
+	Suites   []Suite   `json:"suites"`
+}
+
+// DefaultFile is $XDG_DATA_HOME/checkup/history.jsonl, or ~/.local/share/checkup/history.jsonl
+func DefaultFile() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, _ := os.UserHomeDir()
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "checkup", "history.jsonl")
+}
+
+// NewEntry makes a compact history entry of the run report
+func NewEntry(run report.Run, now time.Time) Entry {
+	hostname, _ := os.Hostname()
+	entry := Entry{
+		Time:     now,
+		Hostname: hostname,
+		Rating:   run.Summary.Rating,
+		Suites:   []Suite{},
+	}
+
+	for _, s := range run.Suites {
+		suite := Suite{
+			Name:                 s.TestName,
+			File:                 s.File,
+			Rating:               s.Summary.Rating,
+			DurationMilliSeconds: s.Summary.DurationMilliSeconds,
+			Cases:                []Case{},
+		}
+		for _, t := range s.Tests {
+			suite.Cases = append(suite.Cases, Case{
+				ID:                   t.ID,
+				Name:                 t.Name,
+				State:                t.State(),
+				DurationMilliSeconds: t.DurationMilliSeconds,
+			})
+		}
+		entry.Suites = append(entry.Suites, suite)
+	}
+
+	return entry
+}
+
+// Run restores the case states of the entry as a run report, so it can be compared with the current one
+func (e Entry) Run() report.Run {
+	run := report.Run{Summary: report.Summary{Rating: e.Rating}}
+
+	for _, s := range e.Suites {
+		suite := report.Suite{TestName: s.Name, File: s.File, Summary: report.Summary{Rating: s.Rating}}
+		for _, c := range s.Cases {
+			t := report.Test{ID: c.ID, Name: c.Name, DurationMilliSeconds: c.DurationMilliSeconds}
+			switch c.State {
+			case scoring.StatusPassed:
+				t.Status = true
+			case scoring.StatusSkipped:
+				t.Skipped = true
+			case scoring.StatusWaived:
+				t.Waived = &waivers.Waiver{}
+			}
+			suite.Tests = append(suite.Tests, t)
+		}
+		run.Suites = append(run.Suites, suite)
+	}
+
+	return run
+}
+
+// Append adds the entry to the history file, creating it if needed
+func Append(fileName string, entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads the history file, keeping 'last' entries only when it's above 0
+func Load(fileName string, last int) ([]Entry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", fileName, line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if last > 0 && len(entries) > last {
+		entries = entries[len(entries)-last:]
+	}
+
+	return entries, nil
+}
+
+// Trend is a series of ratings of the suite
+type Trend struct {
+	Name                     string
+	File                     string
+	Ratings                  []float64
+	MeanDurationMilliSeconds int
+}
+
+// CaseStats aggregates results of the case over all runs
+type CaseStats struct {
+	ID                       string
+	Name                     string
+	Runs                     int
+	States                   []string
+	Flips                    int
+	MeanDurationMilliSeconds int
+	MaxDurationMilliSeconds  int
+}
+
+// Stats is the analysis of the history
+type Stats struct {
+	Runs    int
+	Trends  []Trend
+	Flaky   []CaseStats
+	Slowest []CaseStats
+}
+
+// Analyze calculates suites rating trends, flaky cases (which status changed
+// between passed and failed at least twice) and the slowest cases by mean duration
+func Analyze(entries []Entry, top int) Stats {
+	stats := Stats{Runs: len(entries)}
+
+	trends := map[string]*Trend{}
+	trendsOrder := []string{}
+	durations := map[string]int{}
+
+	cases := map[string]*CaseStats{}
+	casesOrder := []string{}
+
+	for _, entry := range entries {
+		for _, suite := range entry.Suites {
+			key := suite.File + "::" + suite.Name
+			if _, ok := trends[key]; !ok {
+				trends[key] = &Trend{Name: suite.Name, File: suite.File}
+				trendsOrder = append(trendsOrder, key)
+			}
+			trends[key].Ratings = append(trends[key].Ratings, suite.Rating)
+			durations[key] += suite.DurationMilliSeconds
+
+			for _, c := range suite.Cases {
+				if _, ok := cases[c.ID]; !ok {
+					cases[c.ID] = &CaseStats{ID: c.ID, Name: c.Name}
+					casesOrder = append(casesOrder, c.ID)
+				}
+				cs := cases[c.ID]
+				cs.Runs++
+				cs.States = append(cs.States, c.State)
+				cs.MeanDurationMilliSeconds += c.DurationMilliSeconds
+				if c.DurationMilliSeconds > cs.MaxDurationMilliSeconds {
+					cs.MaxDurationMilliSeconds = c.DurationMilliSeconds
+				}
+			}
+		}
+	}
+
+	for _, key := range trendsOrder {
+		t := trends[key]
+		t.MeanDurationMilliSeconds = durations[key] / len(t.Ratings)
+		stats.Trends = append(stats.Trends, *t)
+	}
+
+	all := []CaseStats{}
+	for _, id := range casesOrder {
+		cs := cases[id]
+		cs.MeanDurationMilliSeconds = cs.MeanDurationMilliSeconds / cs.Runs
+
+		previous := ""
+		for _, state := range cs.States {
+			if state != scoring.StatusPassed && state != scoring.StatusFailed {
+				continue
+			}
+			if previous != "" && previous != state {
+				cs.Flips++
+			}
+			previous = state
+		}
+
+		if cs.Flips >= 2 {
+			stats.Flaky = append(stats.Flaky, *cs)
+		}
+		all = append(all, *cs)
+	}
+
+	sort.SliceStable(stats.Flaky, func(i, j int) bool {
+		return stats.Flaky[i].Flips > stats.Flaky[j].Flips
+	})
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].MeanDurationMilliSeconds > all[j].MeanDurationMilliSeconds
+	})
+	if len(all) > top {
+		all = all[:top]
+	}
+	stats.Slowest = all
+
+	return stats
+}
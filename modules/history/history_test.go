@@ -0,0 +1,133 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+// entry makes a history entry of the suite with the case states and durations
+func entry(rating float64, cases ...Case) Entry {
+	return Entry{Rating: rating, Suites: []Suite{{Name: "suite", File: "suite.yaml", Rating: rating, DurationMilliSeconds: 100, Cases: cases}}}
+}
+
+func TestNewEntry(t *testing.T) {
+	run := report.Run{
+		Summary: report.Summary{Rating: 50},
+		Suites: []report.Suite{{
+			TestName: "suite",
+			File:     "suite.yaml",
+			Summary:  report.Summary{Rating: 50, DurationMilliSeconds: 30},
+			Tests: []report.Test{
+				{ID: "suite.yaml::a", Name: "a", Status: true, DurationMilliSeconds: 10},
+				{ID: "suite.yaml::b", Name: "b", DurationMilliSeconds: 20},
+				{ID: "suite.yaml::c", Name: "c", Skipped: true},
+				{ID: "suite.yaml::d", Name: "d", Waived: &waivers.Waiver{Justification: "accepted"}},
+			},
+		}},
+	}
+
+	e := NewEntry(run, time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC))
+	states := []string{}
+	for _, c := range e.Suites[0].Cases {
+		states = append(states, c.State)
+	}
+	want := []string{scoring.StatusPassed, scoring.StatusFailed, scoring.StatusSkipped, scoring.StatusWaived}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+
+	// the restored run has the same case states
+	restored := e.Run()
+	for i, test := range restored.Suites[0].Tests {
+		if test.State() != want[i] || test.ID != run.Suites[0].Tests[i].ID {
+			t.Errorf("restored case %d = %s %s, want %s %s", i, test.ID, test.State(), run.Suites[0].Tests[i].ID, want[i])
+		}
+	}
+}
+
+func TestAppendLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkup", "history.jsonl")
+	for i := 1; i <= 3; i++ {
+		if err := Append(fileName, entry(float64(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		last int
+		want []float64
+	}{
+		{0, []float64{1, 2, 3}},
+		{2, []float64{2, 3}},
+		{5, []float64{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		entries, err := Load(fileName, tt.last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ratings := []float64{}
+		for _, e := range entries {
+			ratings = append(ratings, e.Rating)
+		}
+		if !reflect.DeepEqual(ratings, tt.want) {
+			t.Errorf("Load(last=%d) ratings = %v, want %v", tt.last, ratings, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.jsonl")
+	if err := os.WriteFile(broken, []byte("{\"rating\": 1}\n\n{broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(broken, 0); err == nil || !strings.Contains(err.Error(), broken+":3:") {
+		t.Errorf("Load() error = %v, want the error at line 3", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.jsonl"), 0); !os.IsNotExist(err) {
+		t.Errorf("Load() error = %v, want not exist", err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	passed, failed, skipped := scoring.StatusPassed, scoring.StatusFailed, scoring.StatusSkipped
+	entries := []Entry{
+		entry(100, Case{ID: "stable", State: passed, DurationMilliSeconds: 10}, Case{ID: "flaky", State: passed, DurationMilliSeconds: 100}, Case{ID: "broken", State: passed, DurationMilliSeconds: 50}),
+		entry(50, Case{ID: "stable", State: passed, DurationMilliSeconds: 30}, Case{ID: "flaky", State: failed, DurationMilliSeconds: 300}, Case{ID: "broken", State: failed, DurationMilliSeconds: 50}),
+		entry(75, Case{ID: "stable", State: passed, DurationMilliSeconds: 20}, Case{ID: "flaky", State: skipped}, Case{ID: "broken", State: failed, DurationMilliSeconds: 50}),
+		entry(100, Case{ID: "stable", State: passed, DurationMilliSeconds: 20}, Case{ID: "flaky", State: passed, DurationMilliSeconds: 200}, Case{ID: "broken", State: failed, DurationMilliSeconds: 50}),
+	}
+
+	stats := Analyze(entries, 2)
+
+	if stats.Runs != 4 {
+		t.Errorf("Runs = %d, want 4", stats.Runs)
+	}
+	if len(stats.Trends) != 1 || !reflect.DeepEqual(stats.Trends[0].Ratings, []float64{100, 50, 75, 100}) || stats.Trends[0].MeanDurationMilliSeconds != 100 {
+		t.Errorf("Trends = %+v", stats.Trends)
+	}
+
+	// skipped runs don't break the series of flips: passed, failed, (skipped), passed
+	if len(stats.Flaky) != 1 || stats.Flaky[0].ID != "flaky" || stats.Flaky[0].Flips != 2 {
+		t.Errorf("Flaky = %+v, want the flaky case with 2 flips", stats.Flaky)
+	}
+
+	slowest := []string{}
+	for _, cs := range stats.Slowest {
+		slowest = append(slowest, cs.ID)
+	}
+	if !reflect.DeepEqual(slowest, []string{"flaky", "broken"}) || stats.Slowest[0].MaxDurationMilliSeconds != 300 || stats.Slowest[0].MeanDurationMilliSeconds != 150 {
+		t.Errorf("Slowest = %+v, want flaky (mean 150, max 300) and broken", stats.Slowest)
+	}
+}
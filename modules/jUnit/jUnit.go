@@ -2,22 +2,35 @@ package jUnit
 
 const JUnitTemplate = `<?xml version="1.0" encoding="UTF-8"?>
 <testsuites time="">
-	<testsuite name="{{ .SuitName }}" tests="{{ .TotalTests }}" failures="{{ .FailedTests }}" errors="0" skipped="0" time="{{ .TotalTime }}" timestamp="{{ .TimeStamp }}" hostname="">
+{{- range $s := .Suites }}
+	<testsuite name="{{ Escape $s.Name }}" tests="{{ $s.Tests }}" failures="{{ $s.Failures }}" errors="0" skipped="{{ $s.Skipped }}" time="{{ $s.Time }}" timestamp="{{ $s.TimeStamp }}" hostname="">
+		{{- if $s.Properties }}
+		<properties>
+			{{- range $p := $s.Properties }}
+			<property name="{{ Escape $p.Name }}" value="{{ Escape $p.Value }}"/>
+			{{- end }}
+		</properties>
+		{{- end }}
 
-	{{- $verbosity := .Verbosity }}
-	{{- range $t := .Tests }}
-		{{- if $t.CanShow }}
-			{{- if $t.IsSuccessful }}
-				<testcase classname="{{ $.SuitName }}" name={{ Quote $t.Case }} time="{{ $t.Duration }}">
+	{{- range $t := $s.Cases }}
+				<testcase classname="{{ Escape $s.Name }}" name="{{ Escape $t.Name }}" time="{{ $t.Time }}">
+			{{- if $t.Properties }}
+					<properties>
+				{{- range $p := $t.Properties }}
+						<property name="{{ Escape $p.Name }}" value="{{ Escape $p.Value }}"/>
+				{{- end }}
+					</properties>
+			{{- end }}
+			{{- if eq $t.Status "passed" }}
 					<!-- system-out>STDOUT text</system-out -->
-				</testcase>
+			{{- else if eq $t.Status "skipped" }}
+					<skipped message="{{ Escape $t.Output }}"/>
 			{{- else }}
-				<testcase classname="{{ $.SuitName }}" name={{ $t.Case | Quote }} time="{{ $t.Duration }}">
-					<failure type="failure">{{ Quote $t.Stdout }}</failure>
+					<failure type="failure">{{ Escape $t.Output }}</failure>
+			{{- end }}
 				</testcase>
-			{{-  end }}
-		{{-  end }}
-  {{- end }}
-  </testsuite>
+	{{- end }}
+	</testsuite>
+{{- end }}
 </testsuites>
-`
\ No newline at end of file
+`
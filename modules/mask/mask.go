@@ -0,0 +1,152 @@
+// Package mask hides secret values in the console output and reports
+package mask
+
+import (
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces the secret values
+const Mask = "***"
+
+// MinLength of the masked values, shorter ones would hide too much of the output
+const MinLength = 4
+
+// Patterns of env keys which values are secrets without declaring them in 'secrets' setting, case insensitive
+var Patterns = []string{"*TOKEN*", "*PASSWORD*", "*PASSWD*", "*SECRET*", "*APIKEY*", "*CREDENTIAL*", "*KEY"}
+
+// Exclusions are the keys matching the patterns which aren't secrets, e.g. limits of LLM tokens
+var Exclusions = []string{"*MAX_TOKENS*", "*TOKEN_LIMIT*", "*TOKEN_COUNT*", "*TOKENIZER*"}
+
+// Secret tells whether the env key is declared as a secret, or matches the patterns and none of the exclusions
+func Secret(key string, declared []string) bool {
+	if Declared(key, declared) {
+		return true
+	}
+
+	key = strings.ToUpper(key)
+	for _, pattern := range Exclusions {
+		if matched, _ := path.Match(pattern, key); matched {
+			return false
+		}
+	}
+	for _, pattern := range Patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Declared tells whether the env key is declared as a secret in 'secrets' setting
+func Declared(key string, declared []string) bool {
+	for _, v := range declared {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Masker collects the secret values and replaces them in texts
+type Masker struct {
+	mu       sync.Mutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// Add registers the value of the key matching the patterns, every line of multiline values is masked
+// separately as well; values shorter than MinLength aren't masked, they would hide too much of the output
+func (m *Masker) Add(value string) {
+	m.add(value, MinLength)
+}
+
+// AddSecret registers the value which is known to be a secret: declared in 'secrets' setting,
+// or resolved from the secrets store, it's masked whatever it looks like
+func (m *Masker) AddSecret(value string) {
+	m.add(value, 1)
+}
+
+func (m *Masker) add(value string, minLength int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.values == nil {
+		m.values = map[string]bool{}
+	}
+
+	added := false
+	for _, v := range append([]string{value}, strings.Split(value, "\n")...) {
+		v = strings.TrimSpace(v)
+		if len(v) >= minLength && !m.values[v] {
+			m.values[v] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	// longer values go first, so that a secret containing another one is masked as a whole
+	values := make([]string, 0, len(m.values))
+	for v := range m.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, Mask)
+	}
+	m.replacer = strings.NewReplacer(pairs...)
+}
+
+// String replaces the secret values in the text
+func (m *Masker) String(text string) string {
+	m.mu.Lock()
+	replacer := m.replacer
+	m.mu.Unlock()
+
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// Env replaces the values of secret keys in KEY=value list, whatever their length is
+func (m *Masker) Env(env []string, declared []string) []string {
+	result := make([]string, len(env))
+	for i, v := range env {
+		if key, value, ok := strings.Cut(v, "="); ok && value != "" && Secret(key, declared) {
+			result[i] = key + "=" + Mask
+			continue
+		}
+		result[i] = m.String(v)
+	}
+	return result
+}
+
+// Writer masks the secrets written to w, every write is expected to be a complete line or more, as log.Logger does
+func (m *Masker) Writer(w io.Writer) io.Writer {
+	return writer{w: w, m: m}
+}
+
+type writer struct {
+	w io.Writer
+	m *Masker
+}
+
+func (p writer) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, p.m.String(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/scoring"
+)
+
+// Conditions of sending the notification
+const (
+	OnFailure = "failure"
+	OnChange  = "change"
+	OnAlways  = "always"
+)
+
+// Payload formats, all but 'webhook' are chat incoming webhooks
+const (
+	FormatWebhook    = "webhook"
+	FormatSlack      = "slack"
+	FormatMattermost = "mattermost"
+	FormatTeams      = "teams"
+)
+
+// Sink is a notification target, defined with --notify option or in 'notify' section of the suite
+type Sink struct {
+	URL      string   `yaml:"url"`
+	Format   string   `yaml:"format"`
+	Template string   `yaml:"template"`
+	On       []string `yaml:"on"`
+	Retries  *int     `yaml:"retries"`
+	Timeout  int      `yaml:"timeout"`
+}
+
+// Case is a failed case in the payload
+type Case struct {
+	ID        string `json:"id"`
+	Suite     string `json:"suite"`
+	Name      string `json:"name"`
+	ControlID string `json:"controlId,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Stdout    string `json:"stdout,omitempty"`
+}
+
+// Suite is a suite summary in the payload
+type Suite struct {
+	Name    string  `json:"name"`
+	File    string  `json:"file,omitempty"`
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Waived  int     `json:"waived"`
+	Rating  float64 `json:"rating"`
+}
+
+// Payload is the JSON body of generic webhooks, and the data of payload templates
+type Payload struct {
+	Status   string    `json:"status"`
+	Changed  bool      `json:"changed"`
+	Hostname string    `json:"hostname,omitempty"`
+	Time     time.Time `json:"time"`
+	Summary  string    `json:"summary"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	Skipped  int       `json:"skipped"`
+	Waived   int       `json:"waived"`
+	Rating   float64   `json:"rating"`
+	Duration string    `json:"duration"`
+	Suites   []Suite   `json:"suites"`
+	Failures []Case    `json:"failures"`
+}
+
+var templates = map[string]string{
+	FormatSlack:      `{"text": {{ json (printf "%s\n%s" .Summary (failures .)) }}}`,
+	FormatMattermost: `{"text": {{ json (printf "%s\n%s" .Summary (failures .)) }}}`,
+	FormatTeams: `{
+  "@type": "MessageCard",
+  "@context": "http://schema.org/extensions",
+  "themeColor": "{{ if eq .Status "failed" }}d9534f{{ else }}5cb85c{{ end }}",
+  "summary": {{ json .Summary }},
+  "title": {{ json .Summary }},
+  "text": {{ json (failures .) }}
+}`,
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+	"failures": func(p Payload) string {
+		lines := []string{}
+		for _, c := range p.Failures {
+			lines = append(lines, fmt.Sprintf("✗ [%s] %s", c.Suite, c.Name))
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+// Parse reads --notify option value: format=url, e.g. webhook=https://example.com/hook
+func Parse(value string, on []string) (Sink, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return Sink{}, fmt.Errorf("notification sink '%s' should be defined as format=url", value)
+	}
+
+	sink := Sink{Format: parts[0], URL: parts[1], On: on}
+	return sink, sink.Validate()
+}
+
+// Validate checks the sink definition and sets the defaults
+func (s *Sink) Validate() error {
+	if s.URL == "" {
+		return fmt.Errorf("notification sink has no url")
+	}
+
+	if s.Format == "" {
+		s.Format = FormatWebhook
+	}
+	if _, ok := templates[s.Format]; !ok && s.Format != FormatWebhook {
+		return fmt.Errorf("unknown notification format '%s', expected one of: webhook, slack, mattermost, teams", s.Format)
+	}
+
+	if s.Template != "" {
+		if _, err := template.New("notify").Funcs(funcs).Parse(s.Template); err != nil {
+			return fmt.Errorf("notification template of %s: %v", s.URL, err)
+		}
+	}
+
+	if len(s.On) == 0 {
+		s.On = []string{OnAlways}
+	}
+	for _, on := range s.On {
+		if on != OnFailure && on != OnChange && on != OnAlways {
+			return fmt.Errorf("unknown notification condition '%s', expected one of: failure, change, always", on)
+		}
+	}
+
+	if (s.Retries != nil && *s.Retries < 0) || s.Timeout < 0 {
+		return fmt.Errorf("notification retries and timeout of %s can't be negative", s.URL)
+	}
+	if s.Retries == nil {
+		retries := 2
+		s.Retries = &retries
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 10
+	}
+
+	return nil
+}
+
+// Matches tells whether the notification should be sent for the payload
+func (s Sink) Matches(p Payload) bool {
+	for _, on := range s.On {
+		switch {
+		case on == OnAlways:
+			return true
+		case on == OnFailure && p.Failed > 0:
+			return true
+		case on == OnChange && p.Changed:
+			return true
+		}
+	}
+	return false
+}
+
+// NewPayload summarizes the run report, changed tells whether any case
+// changed its status since the previous run
+func NewPayload(run report.Run, changed bool, now time.Time) Payload {
+	hostname, _ := os.Hostname()
+
+	p := Payload{
+		Status:   scoring.StatusPassed,
+		Changed:  changed,
+		Hostname: hostname,
+		Time:     now,
+		Passed:   run.Summary.Success,
+		Failed:   run.Summary.Failed,
+		Skipped:  run.Summary.Skipped,
+		Waived:   run.Summary.Waived,
+		Rating:   run.Summary.Rating,
+		Duration: run.Summary.Duration,
+		Suites:   []Suite{},
+		Failures: []Case{},
+	}
+
+	names := []string{}
+	for _, s := range run.Suites {
+		names = append(names, s.TestName)
+		p.Suites = append(p.Suites, Suite{
+			Name:    s.TestName,
+			File:    s.File,
+			Passed:  s.Summary.Success,
+			Failed:  s.Summary.Failed,
+			Skipped: s.Summary.Skipped,
+			Waived:  s.Summary.Waived,
+			Rating:  s.Summary.Rating,
+		})
+
+		for _, t := range s.Tests {
+			if t.State() == scoring.StatusFailed {
+				p.Failures = append(p.Failures, Case{
+					ID:        t.ID,
+					Suite:     s.TestName,
+					Name:      t.Name,
+					ControlID: t.ControlID,
+					Severity:  t.Severity,
+					Stdout:    t.Stdout,
+				})
+			}
+		}
+	}
+
+	if p.Failed > 0 {
+		p.Status = scoring.StatusFailed
+	}
+
+	p.Summary = fmt.Sprintf("check-up [ %s ]%s: %d (of %d) tests passed, %d failed, rated as %.2f%%",
+		strings.Join(names, ", "), onHost(hostname), p.Passed, p.Passed+p.Failed+p.Skipped+p.Waived, p.Failed, p.Rating)
+
+	return p
+}
+
+func onHost(hostname string) string {
+	if hostname == "" {
+		return ""
+	}
+	return " on " + hostname
+}
+
+// Body renders the request body: custom template, format template, or the payload itself
+func (s Sink) Body(p Payload) ([]byte, error) {
+	text := s.Template
+	if text == "" {
+		text = templates[s.Format]
+	}
+
+	if text == "" {
+		return json.Marshal(p)
+	}
+
+	tmpl, err := template.New("notify").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Send posts the payload, retrying on connection errors, 429 and 5xx responses
+func (s Sink) Send(p Payload) error {
+	body, err := s.Body(p)
+	if err != nil {
+		return fmt.Errorf("notification to %s: %v", s.URL, err)
+	}
+
+	client := &http.Client{Timeout: time.Duration(s.Timeout) * time.Second}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Post(s.URL, "application/json", bytes.NewReader(body))
+		retry := err != nil
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			switch {
+			case resp.StatusCode < 300:
+				return nil
+			case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+				retry = true
+			}
+			err = fmt.Errorf("unexpected response status: %s", resp.Status)
+		}
+
+		if !retry || s.Retries == nil || attempt >= *s.Retries {
+			return fmt.Errorf("notification to %s: %v", s.URL, err)
+		}
+
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+}
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		on         []string
+		wantFormat string
+		wantErr    bool
+	}{
+		{"webhook", "webhook=https://example.com/hook", nil, FormatWebhook, false},
+		{"url with '='", "slack=https://example.com/hook?a=b", []string{OnFailure}, FormatSlack, false},
+		{"no url", "webhook=", nil, "", true},
+		{"no format", "https://example.com/hook", nil, "", true},
+		{"unknown format", "email=https://example.com/hook", nil, "", true},
+		{"unknown condition", "teams=https://example.com/hook", []string{"sometimes"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, err := Parse(tt.value, tt.on)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && sink.Format != tt.wantFormat {
+				t.Errorf("Parse() format = %s, want %s", sink.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		sink        Sink
+		wantRetries int
+		wantErr     bool
+	}{
+		{"default retries", Sink{URL: "http://localhost"}, 2, false},
+		{"retries disabled", Sink{URL: "http://localhost", Retries: intPtr(0)}, 0, false},
+		{"negative retries", Sink{URL: "http://localhost", Retries: intPtr(-1)}, 0, true},
+		{"negative timeout", Sink{URL: "http://localhost", Timeout: -1}, 0, true},
+		{"invalid template", Sink{URL: "http://localhost", Template: "{{ .Summary "}, 0, true},
+		{"no url", Sink{}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sink.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *tt.sink.Retries != tt.wantRetries {
+				t.Errorf("Retries = %d, want %d", *tt.sink.Retries, tt.wantRetries)
+			}
+			if tt.sink.Timeout != 10 || len(tt.sink.On) != 1 || tt.sink.On[0] != OnAlways {
+				t.Errorf("defaults: Timeout = %d, On = %v, want 10, [always]", tt.sink.Timeout, tt.sink.On)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		on      []string
+		payload Payload
+		want    bool
+	}{
+		{"always", []string{OnAlways}, Payload{}, true},
+		{"failure without failures", []string{OnFailure}, Payload{Changed: true}, false},
+		{"failure", []string{OnFailure}, Payload{Failed: 1}, true},
+		{"change without changes", []string{OnChange}, Payload{Failed: 1}, false},
+		{"change", []string{OnChange}, Payload{Changed: true}, true},
+		{"any of conditions", []string{OnChange, OnFailure}, Payload{Failed: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Sink{On: tt.on}).Matches(tt.payload); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	payload := Payload{
+		Status:   "failed",
+		Summary:  `check-up [ "quoted" ]`,
+		Failures: []Case{{Suite: "suite", Name: "case"}},
+	}
+
+	for _, format := range []string{FormatWebhook, FormatSlack, FormatMattermost, FormatTeams} {
+		t.Run(format, func(t *testing.T) {
+			body, err := (Sink{Format: format}).Body(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !json.Valid(body) {
+				t.Errorf("Body() is not valid JSON: %s", body)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		retries      int
+		wantRequests int
+		wantErr      bool
+	}{
+		{"accepted", http.StatusOK, 2, 1, false},
+		{"client error isn't retried", http.StatusBadRequest, 2, 1, true},
+		{"retries disabled", http.StatusServiceUnavailable, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sink := Sink{URL: server.URL, Retries: intPtr(tt.retries)}
+			if err := sink.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := sink.Send(Payload{}); (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
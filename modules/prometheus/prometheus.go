@@ -0,0 +1,126 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/scoring"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type label struct {
+	name  string
+	value string
+}
+
+func labels(l ...label) string {
+	pairs := []string{}
+	for _, v := range l {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, v.name, labelEscaper.Replace(v.value)))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// caseStatus encodes the case state: 1 - passed, 0 - failed, 2 - waived, -1 - skipped
+func caseStatus(state string) int {
+	switch state {
+	case scoring.StatusPassed:
+		return 1
+	case scoring.StatusWaived:
+		return 2
+	case scoring.StatusSkipped:
+		return -1
+	}
+	return 0
+}
+
+// Format renders the run report in Prometheus text exposition format
+func Format(run report.Run, now time.Time) []byte {
+	var buf bytes.Buffer
+
+	metric := func(name string, help string, kind string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("checkup_case_status", "Case status: 1 - passed, 0 - failed, 2 - waived, -1 - skipped", "gauge")
+	for _, s := range run.Suites {
+		for _, t := range s.Tests {
+			fmt.Fprintf(&buf, "checkup_case_status%s %d\n", labels(
+				label{"suite", s.TestName}, label{"case", t.Name}, label{"control", t.ControlID}, label{"id", t.ID},
+			), caseStatus(t.State()))
+		}
+	}
+
+	metric("checkup_case_duration_seconds", "Case execution time", "gauge")
+	for _, s := range run.Suites {
+		for _, t := range s.Tests {
+			fmt.Fprintf(&buf, "checkup_case_duration_seconds%s %g\n", labels(
+				label{"suite", s.TestName}, label{"case", t.Name}, label{"control", t.ControlID}, label{"id", t.ID},
+			), float64(t.DurationMilliSeconds)/1000)
+		}
+	}
+
+	metric("checkup_suite_score", "Suite rating, percent", "gauge")
+	for _, s := range run.Suites {
+		fmt.Fprintf(&buf, "checkup_suite_score%s %g\n", labels(label{"suite", s.TestName}, label{"file", s.File}), s.Summary.Rating)
+	}
+
+	metric("checkup_suite_cases", "Number of suite cases by state", "gauge")
+	for _, s := range run.Suites {
+		for _, v := range []struct {
+			state string
+			count int
+		}{
+			{scoring.StatusPassed, s.Summary.Success},
+			{scoring.StatusFailed, s.Summary.Failed},
+			{scoring.StatusSkipped, s.Summary.Skipped},
+			{scoring.StatusWaived, s.Summary.Waived},
+		} {
+			fmt.Fprintf(&buf, "checkup_suite_cases%s %d\n", labels(label{"suite", s.TestName}, label{"file", s.File}, label{"state", v.state}), v.count)
+		}
+	}
+
+	metric("checkup_suite_duration_seconds", "Suite execution time", "gauge")
+	for _, s := range run.Suites {
+		fmt.Fprintf(&buf, "checkup_suite_duration_seconds%s %g\n", labels(label{"suite", s.TestName}, label{"file", s.File}), float64(s.Summary.DurationMilliSeconds)/1000)
+	}
+
+	metric("checkup_score", "Rating of all suites of the run, percent", "gauge")
+	fmt.Fprintf(&buf, "checkup_score %g\n", run.Summary.Rating)
+
+	metric("checkup_last_run_timestamp_seconds", "Time of the last run, unix timestamp", "gauge")
+	fmt.Fprintf(&buf, "checkup_last_run_timestamp_seconds %d\n", now.Unix())
+
+	return buf.Bytes()
+}
+
+// Save writes metrics to the temporary file next to the target and renames it,
+// so textfile collector never reads partially written file
+func Save(fileName string, run report.Run, now time.Time) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(Format(run, now)); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), fileName)
+}
@@ -0,0 +1,89 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbeliakou/check-up/modules/report"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+var now = time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+
+func testRun() report.Run {
+	return report.Run{
+		Summary: report.Summary{Rating: 62.5},
+		Suites: []report.Suite{{
+			TestName: "Web server",
+			File:     "web.yaml",
+			Summary:  report.Summary{Success: 1, Failed: 1, Skipped: 1, Waived: 1, Rating: 62.5, DurationMilliSeconds: 1500},
+			Tests: []report.Test{
+				{ID: "web.yaml::1.1", Name: "Port is open", ControlID: "1.1", Status: true, DurationMilliSeconds: 250},
+				{ID: "web.yaml::Config \"main\"", Name: "Config \"main\"\nis valid", DurationMilliSeconds: 1000},
+				{ID: "web.yaml::skipped", Name: `C:\path`, Skipped: true},
+				{ID: "web.yaml::waived", Name: "Waived", Waived: &waivers.Waiver{}},
+			},
+		}},
+	}
+}
+
+func TestFormat(t *testing.T) {
+	text := string(Format(testRun(), now))
+
+	tests := []string{
+		"# HELP checkup_case_status Case status: 1 - passed, 0 - failed, 2 - waived, -1 - skipped\n# TYPE checkup_case_status gauge\n",
+		`checkup_case_status{suite="Web server",case="Port is open",control="1.1",id="web.yaml::1.1"} 1` + "\n",
+		`checkup_case_status{suite="Web server",case="Config \"main\"\nis valid",control="",id="web.yaml::Config \"main\""} 0` + "\n",
+		`checkup_case_status{suite="Web server",case="C:\\path",control="",id="web.yaml::skipped"} -1` + "\n",
+		`checkup_case_status{suite="Web server",case="Waived",control="",id="web.yaml::waived"} 2` + "\n",
+		`checkup_case_duration_seconds{suite="Web server",case="Port is open",control="1.1",id="web.yaml::1.1"} 0.25` + "\n",
+		`checkup_suite_score{suite="Web server",file="web.yaml"} 62.5` + "\n",
+		`checkup_suite_cases{suite="Web server",file="web.yaml",state="waived"} 1` + "\n",
+		`checkup_suite_duration_seconds{suite="Web server",file="web.yaml"} 1.5` + "\n",
+		"checkup_score 62.5\n",
+		"checkup_last_run_timestamp_seconds 1773136800\n",
+	}
+
+	for _, want := range tests {
+		if !strings.Contains(text, want) {
+			t.Errorf("metrics don't contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "checkup.prom")
+	if err := os.WriteFile(fileName, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Save(fileName, testRun(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(Format(testRun(), now)) {
+		t.Errorf("saved metrics = %q", data)
+	}
+
+	info, _ := os.Stat(fileName)
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644 for the textfile collector", info.Mode().Perm())
+	}
+
+	// the temporary file is renamed, nothing is left behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d files, want 1", len(entries))
+	}
+
+	if err := Save(filepath.Join(dir, "missing", "checkup.prom"), testRun(), now); err == nil {
+		t.Error("Save() to a missing directory error = nil, want an error")
+	}
+}
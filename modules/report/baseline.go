@@ -0,0 +1,86 @@
+package report
+
+import (
+	"github.com/sbeliakou/check-up/modules/scoring"
+)
+
+// Change is a case which status differs from the baseline, or which was added or removed
+type Change struct {
+	ID     string `json:"id"`
+	Suite  string `json:"suite"`
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Comparison of the current run with a baseline report
+type Comparison struct {
+	File         string   `json:"file"`
+	RatingBefore float64  `json:"ratingBefore"`
+	RatingAfter  float64  `json:"ratingAfter"`
+	RatingDelta  float64  `json:"ratingDelta"`
+	NewlyFailing []Change `json:"newlyFailing"`
+	NewlyPassing []Change `json:"newlyPassing"`
+	Added        []Change `json:"added"`
+	Removed      []Change `json:"removed"`
+}
+
+// Regressions is a number of cases which were passing (or skipped, or waived) in the baseline and fail now
+func (c *Comparison) Regressions() int {
+	return len(c.NewlyFailing)
+}
+
+// Compare matches the cases of both runs by their ids
+func Compare(baseline Run, current Run) Comparison {
+	comparison := Comparison{
+		RatingBefore: baseline.Summary.Rating,
+		RatingAfter:  current.Summary.Rating,
+		RatingDelta:  current.Summary.Rating - baseline.Summary.Rating,
+		NewlyFailing: []Change{},
+		NewlyPassing: []Change{},
+		Added:        []Change{},
+		Removed:      []Change{},
+	}
+
+	before := map[string]string{}
+	for _, suite := range baseline.Suites {
+		for _, t := range suite.Tests {
+			before[t.ID] = t.State()
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, suite := range current.Suites {
+		for _, t := range suite.Tests {
+			seen[t.ID] = true
+			change := Change{ID: t.ID, Suite: suite.TestName, Name: t.Name, After: t.State()}
+
+			state, ok := before[t.ID]
+			change.Before = state
+
+			switch {
+			case !ok:
+				comparison.Added = append(comparison.Added, change)
+			case state != scoring.StatusFailed && change.After == scoring.StatusFailed:
+				comparison.NewlyFailing = append(comparison.NewlyFailing, change)
+			case state == scoring.StatusFailed && change.After == scoring.StatusPassed:
+				comparison.NewlyPassing = append(comparison.NewlyPassing, change)
+			}
+		}
+	}
+
+	for _, suite := range baseline.Suites {
+		for _, t := range suite.Tests {
+			if !seen[t.ID] {
+				comparison.Removed = append(comparison.Removed, Change{ID: t.ID, Suite: suite.TestName, Name: t.Name, Before: t.State()})
+			}
+		}
+	}
+
+	return comparison
+}
+
+// Changed tells whether any case failed, got fixed, was added or removed since the baseline
+func (c *Comparison) Changed() bool {
+	return len(c.NewlyFailing)+len(c.NewlyPassing)+len(c.Added)+len(c.Removed) > 0
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+func run(rating float64, tests ...Test) Run {
+	summary := Summary{Rating: rating}
+	return Run{
+		Suites:  []Suite{{TestName: "suite", Tests: tests, Summary: summary}},
+		Summary: summary,
+	}
+}
+
+func ids(changes []Change) []string {
+	result := []string{}
+	for _, c := range changes {
+		result = append(result, c.ID)
+	}
+	return result
+}
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		name string
+		test Test
+		want string
+	}{
+		{"passed", Test{Status: true}, "passed"},
+		{"failed", Test{}, "failed"},
+		{"skipped", Test{Skipped: true}, "skipped"},
+		{"waived", Test{Waived: &waivers.Waiver{}}, "waived"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.test.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := run(50,
+		Test{ID: "still-passing", Status: true},
+		Test{ID: "regressed", Status: true},
+		Test{ID: "skipped-then-failed", Skipped: true},
+		Test{ID: "fixed"},
+		Test{ID: "still-failing"},
+		Test{ID: "removed", Status: true},
+	)
+	current := run(75,
+		Test{ID: "still-passing", Status: true},
+		Test{ID: "regressed"},
+		Test{ID: "skipped-then-failed"},
+		Test{ID: "fixed", Status: true},
+		Test{ID: "still-failing"},
+		Test{ID: "added"},
+	)
+
+	comparison := Compare(baseline, current)
+
+	tests := []struct {
+		name    string
+		changes []Change
+		want    []string
+	}{
+		{"newly failing", comparison.NewlyFailing, []string{"regressed", "skipped-then-failed"}},
+		{"newly passing", comparison.NewlyPassing, []string{"fixed"}},
+		{"added", comparison.Added, []string{"added"}},
+		{"removed", comparison.Removed, []string{"removed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	if comparison.RatingDelta != 25 {
+		t.Errorf("RatingDelta = %v, want 25", comparison.RatingDelta)
+	}
+	if comparison.Regressions() != 2 || !comparison.Changed() {
+		t.Errorf("Regressions() = %d, Changed() = %v, want 2, true", comparison.Regressions(), comparison.Changed())
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	r := run(100, Test{ID: "a", Status: true}, Test{ID: "b", Skipped: true})
+	if comparison := Compare(r, r); comparison.Changed() {
+		t.Errorf("Changed() = true, want false: %+v", comparison)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	single := run(100, Test{ID: "a", Status: true})
+	multi := Run{Suites: []Suite{{TestName: "one"}, {TestName: "two"}}, Summary: Summary{Rating: 50}}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantSuites int
+		wantErr    bool
+	}{
+		{"single suite", single.Marshal(), 1, false},
+		{"multiple suites", multi.Marshal(), 2, false},
+		{"not a report", []byte("[1, 2]"), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(fileName, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := Load(fileName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(loaded.Suites) != tt.wantSuites {
+				t.Errorf("Load() suites = %d, want %d", len(loaded.Suites), tt.wantSuites)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sbeliakou/check-up/modules/expect"
+	"github.com/sbeliakou/check-up/modules/scoring"
+	"github.com/sbeliakou/check-up/modules/snapshot"
+	"github.com/sbeliakou/check-up/modules/waivers"
+)
+
+type Test struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Item     string `json:"item,omitempty"`
+	Status   bool   `json:"status"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Duration string `json:"duration"`
+	Stdout   string `json:"stdout"`
+	Weight   int    `json:"weight"`
+	Category string `json:"category,omitempty"`
+	Severity string `json:"severity,omitempty"`
+
+	ControlID   string   `json:"controlId,omitempty"`
+	Level       string   `json:"level,omitempty"`
+	Rationale   string   `json:"rationale,omitempty"`
+	Audit       string   `json:"audit,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+	References  []string `json:"references,omitempty"`
+
+	RemediationStatus string `json:"remediationStatus,omitempty"`
+
+	Waived *waivers.Waiver `json:"waived,omitempty"`
+
+	Expectations []expect.Result  `json:"expectations,omitempty"`
+	Snapshot     *snapshot.Result `json:"snapshot,omitempty"`
+
+	DurationMilliSeconds int `json:"durationMilliSeconds"`
+}
+
+// State is one of passed, failed, skipped or waived
+func (t Test) State() string {
+	switch {
+	case t.Skipped:
+		return scoring.StatusSkipped
+	case t.Status:
+		return scoring.StatusPassed
+	case t.Waived != nil:
+		return scoring.StatusWaived
+	}
+	return scoring.StatusFailed
+}
+
+type Summary struct {
+	Success    int                `json:"success"`
+	Failed     int                `json:"failed"`
+	Skipped    int                `json:"skipped"`
+	Waived     int                `json:"waived"`
+	Rating     float64            `json:"rating"`
+	Duration   string             `json:"duration"`
+	Categories []scoring.Category `json:"categories,omitempty"`
+
+	DurationMilliSeconds int `json:"durationMilliSeconds"`
+}
+
+type Suite struct {
+	TestName string            `json:"testName"`
+	File     string            `json:"file,omitempty"`
+	Tests    []Test            `json:"tests"`
+	Summary  Summary           `json:"summary"`
+	Waivers  []*waivers.Waiver `json:"waivers,omitempty"`
+	Baseline *Comparison       `json:"baseline,omitempty"`
+}
+
+// Run is saved when there's more than one suite in the run
+type Run struct {
+	Suites   []Suite           `json:"suites"`
+	Summary  Summary           `json:"summary"`
+	Waivers  []*waivers.Waiver `json:"waivers,omitempty"`
+	Baseline *Comparison       `json:"baseline,omitempty"`
+}
+
+// Marshal saves a single suite run in the same format as before, when there were no multi-suite reports
+func (r Run) Marshal() []byte {
+	var data []byte
+
+	if len(r.Suites) == 1 {
+		suite := r.Suites[0]
+		suite.Waivers = r.Waivers
+		suite.Baseline = r.Baseline
+		data, _ = json.MarshalIndent(suite, "", "  ")
+	} else {
+		data, _ = json.MarshalIndent(r, "", "  ")
+	}
+
+	return data
+}
+
+// Load reads JSON report of either a single suite or a multi-suite run
+func Load(fileName string) (Run, error) {
+	var run Run
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return run, err
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return run, fmt.Errorf("cannot recognize report structure in file: %s", fileName)
+	}
+
+	if _, ok := probe["suites"]; ok {
+		err = json.Unmarshal(data, &run)
+	} else {
+		var suite Suite
+		err = json.Unmarshal(data, &suite)
+		run.Suites = []Suite{suite}
+		run.Summary = suite.Summary
+	}
+
+	if err != nil {
+		return run, fmt.Errorf("cannot recognize report structure in file: %s", fileName)
+	}
+
+	return run, nil
+}
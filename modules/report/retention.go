@@ -0,0 +1,48 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SaveRetained saves the report into the directory, keeping 'keep' latest reports only
+func SaveRetained(dir string, data []byte, now time.Time, keep int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(dir, now.UTC().Format("20060102T150405.000Z")+".json")
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return err
+	}
+
+	files := retained(dir)
+	for len(files) > keep && keep > 0 {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// LatestRetained loads the most recent report saved in the directory
+func LatestRetained(dir string) (Run, bool) {
+	files := retained(dir)
+	if len(files) == 0 {
+		return Run{}, false
+	}
+
+	run, err := Load(files[len(files)-1])
+	return run, err == nil
+}
+
+// retained lists saved reports, the oldest first
+func retained(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(files)
+	return files
+}
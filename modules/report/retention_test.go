@@ -0,0 +1,49 @@
+package report
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveRetained(t *testing.T) {
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		saves int
+		keep  int
+		want  int
+	}{
+		{"below the limit", 2, 5, 2},
+		{"oldest removed", 5, 3, 3},
+		{"unlimited", 4, 0, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "results")
+			for i := 0; i < tt.saves; i++ {
+				data := run(float64(i), Test{ID: "a"}).Marshal()
+				if err := SaveRetained(dir, data, start.Add(time.Duration(i)*time.Minute), tt.keep); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if files := retained(dir); len(files) != tt.want {
+				t.Errorf("retained %d reports, want %d: %v", len(files), tt.want, files)
+			}
+
+			latest, ok := LatestRetained(dir)
+			if !ok || latest.Summary.Rating != float64(tt.saves-1) {
+				t.Errorf("LatestRetained() = rating %v, %v, want %v, true", latest.Summary.Rating, ok, tt.saves-1)
+			}
+		})
+	}
+}
+
+func TestLatestRetainedEmpty(t *testing.T) {
+	if _, ok := LatestRetained(t.TempDir()); ok {
+		t.Error("LatestRetained() ok = true, want false")
+	}
+}
@@ -0,0 +1,231 @@
+// Package resolve replaces references in environment values of suites and cases:
+//
+//	{GLOBAL:NAME}            variable of check-up process environment
+//	{GLOBAL:NAME:-default}   the default is used when the variable is unset or empty
+//	{GLOBAL:NAME:?}          the variable is required, the case fails when it's unset or empty
+//	{FILE:/path}             content of the file
+//	{CMD:command}            output of the shell command
+//	{SECRET:name}            value of the secret from the store
+//
+// References can be a part of the value, e.g. "Bearer {SECRET:api/token}", braces inside them have to be
+// balanced, e.g. {CMD:awk '{print $1}' /etc/hostname}; references aren't nested
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout of CMD references in seconds, when the resolver has no timeout set
+const DefaultTimeout = 30
+
+// kinds of the references, '{KIND:' starts the reference
+var kinds = []string{"GLOBAL", "FILE", "CMD", "SECRET"}
+
+// Resolver resolves the references, results of commands and secrets are cached for the resolver lifetime
+type Resolver struct {
+	// Lookup finds GLOBAL variables, os.LookupEnv by default
+	Lookup func(string) (string, bool)
+	// Secrets is the store of SECRET references, the process environment by default
+	Secrets Store
+	// Workdir of CMD references, the current directory if it's empty
+	Workdir string
+	// Timeout of CMD references in seconds, DefaultTimeout if it's not set
+	Timeout int
+	// Required makes every unresolved reference an error which fails the case, instead of an empty value
+	Required bool
+	// OnSecret receives the values of SECRET references when they're resolved, e.g. to mask them in the output
+	OnSecret func(string)
+
+	cache map[string]string
+}
+
+// Error of the reference which can't be resolved
+type Error struct {
+	Reference string
+	Err       error
+	// Required references fail the case, others are resolved as empty values with a warning
+	Required bool
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reference, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Value resolves the references of the value, unresolved ones are replaced with empty strings
+// and the error of the first of them is returned
+func (r *Resolver) Value(value string) (string, error) {
+	var first *Error
+	var b strings.Builder
+
+	for i := 0; i < len(value); {
+		kind, end := reference(value, i)
+		if kind == "" {
+			b.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		// the rest of the value is kept when the reference isn't closed
+		if end < 0 {
+			if first == nil {
+				first = &Error{Reference: value[i:], Err: fmt.Errorf("missing closing '}', braces inside the reference have to be balanced"), Required: r.Required}
+			}
+			b.WriteString(value[i:])
+			break
+		}
+
+		resolved, err := r.resolve(value[i:end], kind, strings.TrimSpace(value[i+len(kind)+2:end-1]))
+		if err != nil && first == nil {
+			first = err
+		}
+		b.WriteString(resolved)
+		i = end
+	}
+
+	if first != nil {
+		return b.String(), first
+	}
+	return b.String(), nil
+}
+
+// reference finds the reference starting at the index, its kind is empty when there's none,
+// end is the index after the closing brace, or -1 when the reference isn't closed
+func reference(value string, start int) (string, int) {
+	if value[start] != '{' {
+		return "", -1
+	}
+
+	for _, kind := range kinds {
+		if !strings.HasPrefix(value[start+1:], kind+":") {
+			continue
+		}
+
+		depth := 1
+		for i := start + len(kind) + 2; i < len(value); i++ {
+			switch value[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return kind, i + 1
+				}
+			}
+		}
+		return kind, -1
+	}
+	return "", -1
+}
+
+func (r *Resolver) resolve(ref string, kind string, arg string) (string, *Error) {
+	fail := func(required bool, format string, args ...interface{}) (string, *Error) {
+		return "", &Error{Reference: ref, Err: fmt.Errorf(format, args...), Required: required || r.Required}
+	}
+
+	switch kind {
+	case "GLOBAL":
+		name, modifier := arg, ""
+		if i := strings.Index(arg, ":"); i >= 0 {
+			name, modifier = arg[:i], arg[i:]
+		}
+
+		lookup := r.Lookup
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		value, ok := lookup(name)
+
+		switch {
+		case strings.HasPrefix(modifier, ":-"):
+			if value == "" {
+				return strings.TrimPrefix(modifier, ":-"), nil
+			}
+		case strings.HasPrefix(modifier, ":?"):
+			if value == "" {
+				if message := strings.TrimSpace(strings.TrimPrefix(modifier, ":?")); message != "" {
+					return fail(true, "%s", message)
+				}
+				return fail(true, "there's no such variable %s", name)
+			}
+		case modifier != "":
+			return fail(true, "unknown modifier '%s', expected ':-default' or ':?'", modifier)
+		case !ok:
+			return fail(false, "there's no such variable %s", name)
+		}
+		return value, nil
+
+	case "FILE":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return fail(false, "%v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	// commands and secrets are cached, they can be slow or ask for a passphrase
+	if value, ok := r.cache[ref]; ok {
+		return value, nil
+	}
+
+	var value string
+	switch kind {
+	case "CMD":
+		timeout := r.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+		defer cancel()
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", arg)
+		cmd.Dir = r.Workdir
+		cmd.Stderr = &stderr
+		// background processes of the command keep its output open, they aren't waited for
+		cmd.WaitDelay = time.Second
+		out, err := cmd.Output()
+		if errors.Is(err, exec.ErrWaitDelay) {
+			err = nil
+		}
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return fail(false, "timed out after %d seconds", timeout)
+		}
+		if err != nil {
+			if text := strings.TrimSpace(stderr.String()); text != "" {
+				err = fmt.Errorf("%v: %s", err, text)
+			}
+			return fail(false, "%v", err)
+		}
+		value = strings.TrimRight(string(out), "\r\n")
+
+	case "SECRET":
+		store := r.Secrets
+		if store == nil {
+			store = EnvStore{}
+		}
+		var err error
+		if value, err = store.Secret(arg); err != nil {
+			return fail(false, "%v", err)
+		}
+		if r.OnSecret != nil {
+			r.OnSecret(value)
+		}
+	}
+
+	if r.cache == nil {
+		r.cache = map[string]string{}
+	}
+	r.cache[ref] = value
+	return value, nil
+}
@@ -0,0 +1,136 @@
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mapStore is a store of the fixed secrets
+type mapStore map[string]string
+
+func (s mapStore) Secret(name string) (string, error) {
+	if value, ok := s[name]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s: not found", name)
+}
+
+func TestValue(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hostname"), []byte("web-01 primary\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	variables := map[string]string{"REGION": "eu-central-1", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		name         string
+		value        string
+		required     bool
+		want         string
+		wantErr      bool
+		wantRequired bool
+	}{
+		{"no references", "plain {value} with {braces}", false, "plain {value} with {braces}", false, false},
+		{"global", "{GLOBAL:REGION}", false, "eu-central-1", false, false},
+		{"part of the value", "region={GLOBAL: REGION }!", false, "region=eu-central-1!", false, false},
+		{"empty global", "[{GLOBAL:EMPTY}]", false, "[]", false, false},
+		{"unset global", "[{GLOBAL:UNSET}]", false, "[]", true, false},
+		{"unset global required by resolver", "{GLOBAL:UNSET}", true, "", true, true},
+		{"default of unset", "{GLOBAL:UNSET:-us-east-1}", false, "us-east-1", false, false},
+		{"default of empty", "{GLOBAL:EMPTY:-us-east-1}", false, "us-east-1", false, false},
+		{"default of set", "{GLOBAL:REGION:-us-east-1}", false, "eu-central-1", false, false},
+		{"default with colon", "{GLOBAL:UNSET:-http://localhost}", false, "http://localhost", false, false},
+		{"required set", "{GLOBAL:REGION:?}", false, "eu-central-1", false, false},
+		{"required unset", "{GLOBAL:UNSET:?}", false, "", true, true},
+		{"required empty with message", "{GLOBAL:EMPTY:? set EMPTY to the region}", false, "", true, true},
+		{"unknown modifier", "{GLOBAL:REGION:+x}", false, "", true, true},
+		{"file", "{FILE:" + dir + "/hostname}", false, "web-01 primary", false, false},
+		{"missing file", "{FILE:" + dir + "/missing}", false, "", true, false},
+		{"command", "{CMD:echo hello}", false, "hello", false, false},
+		{"command in workdir", "{CMD:cat hostname}", false, "web-01 primary", false, false},
+		{"command with braces", "{CMD:awk '{print $1}' hostname}", false, "web-01", false, false},
+		{"failed command", "{CMD:exit 3}", false, "", true, false},
+		{"secret", "Bearer {SECRET:api/token}", false, "Bearer s3cr3t", false, false},
+		{"missing secret", "{SECRET:api/missing}", false, "", true, false},
+		{"several references", "{GLOBAL:UNSET}{GLOBAL:REGION}/{SECRET:api/token}", false, "eu-central-1/s3cr3t", true, false},
+		{"unclosed reference", "a {CMD:awk '{print $1}' hostname", false, "a {CMD:awk '{print $1}' hostname", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Resolver{Lookup: lookup, Secrets: mapStore{"api/token": "s3cr3t"}, Workdir: dir, Required: tt.required}
+
+			got, err := r.Value(tt.value)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("Value() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+			var e *Error
+			if err != nil && (!errors.As(err, &e) || e.Required != tt.wantRequired) {
+				t.Errorf("Value() error = %#v, want Required %v", err, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestValueCache(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	secrets := []string{}
+	r := &Resolver{Secrets: mapStore{"token": "s3cr3t"}, OnSecret: func(value string) {
+		secrets = append(secrets, value)
+	}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Value("{CMD:echo x >> " + counter + "} {SECRET:token}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "x\n" {
+		t.Errorf("the command ran %d times, want once", len(data)/2)
+	}
+	if len(secrets) != 1 || secrets[0] != "s3cr3t" {
+		t.Errorf("OnSecret() received %v, want the secret once", secrets)
+	}
+}
+
+func TestValueCommandTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+		wantErr string
+	}{
+		{"timed out", "{CMD:sleep 5}", "", "timed out after 1 seconds"},
+		{"background process", "{CMD:sleep 5 & echo started}", "started", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := (&Resolver{Timeout: 1}).Value(tt.command)
+			if elapsed := time.Since(start); elapsed > 4*time.Second {
+				t.Errorf("Value() took %v, want it stopped after the timeout", elapsed)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Value() error = %v, want %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Value() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package resolve
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sbeliakou/check-up/modules/dotenv"
+)
+
+// Store provides the values of SECRET references
+type Store interface {
+	Secret(name string) (string, error)
+}
+
+// NewStore makes the store from --secrets option:
+//
+//	env[:PREFIX]        process environment variable PREFIX + name, e.g. env:CHECKUP_SECRET_
+//	file:/path          dotenv file of NAME=value lines
+//	dir:/path           pass-like vault, a file per secret, e.g. /path/api/token or /path/api/token.gpg
+func NewStore(spec string) (Store, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "env":
+		return EnvStore{Prefix: arg}, nil
+	case "file":
+		if arg == "" {
+			return nil, fmt.Errorf("secrets store 'file' requires the path, e.g. file:secrets.env")
+		}
+		return &FileStore{Path: arg}, nil
+	case "dir":
+		if arg == "" {
+			home, _ := os.UserHomeDir()
+			arg = filepath.Join(home, ".password-store")
+		}
+		return DirStore{Dir: arg}, nil
+	}
+	return nil, fmt.Errorf("unknown secrets store '%s', expected one of: env, file, dir", kind)
+}
+
+// EnvStore reads the secrets from the process environment
+type EnvStore struct {
+	Prefix string
+}
+
+func (s EnvStore) Secret(name string) (string, error) {
+	if value, ok := os.LookupEnv(s.Prefix + name); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s: there's no such variable %s", name, s.Prefix+name)
+}
+
+// FileStore reads the secrets from the dotenv file, it's loaded once
+type FileStore struct {
+	Path string
+
+	values map[string]string
+}
+
+func (s *FileStore) Secret(name string) (string, error) {
+	if s.values == nil {
+		values, err := dotenv.Load([]dotenv.File{{Path: s.Path}}, nil)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %v", name, err)
+		}
+		s.values = values
+	}
+
+	if value, ok := s.values[name]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s: not found in %s", name, s.Path)
+}
+
+// DirStore reads the secrets from the files of the directory as pass does: the first line is the secret,
+// files with .gpg extension are decrypted with gpg
+type DirStore struct {
+	Dir string
+}
+
+func (s DirStore) Secret(name string) (string, error) {
+	path := filepath.Join(s.Dir, filepath.Clean("/"+name))
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if _, statErr := os.Stat(path + ".gpg"); statErr != nil {
+			return "", fmt.Errorf("secret %s: not found in %s", name, s.Dir)
+		}
+
+		var stderr bytes.Buffer
+		cmd := exec.Command("gpg", "--quiet", "--batch", "--decrypt", path+".gpg")
+		cmd.Stderr = &stderr
+		if data, err = cmd.Output(); err != nil {
+			return "", fmt.Errorf("secret %s: gpg: %v %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %v", name, err)
+	}
+
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimRight(line, "\r"), nil
+}
@@ -0,0 +1,82 @@
+package resolve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Store
+		wantErr bool
+	}{
+		{"", EnvStore{}, false},
+		{"env:CHECKUP_SECRET_", EnvStore{Prefix: "CHECKUP_SECRET_"}, false},
+		{"file:secrets.env", &FileStore{Path: "secrets.env"}, false},
+		{"file", nil, true},
+		{"dir:/vault", DirStore{Dir: "/vault"}, false},
+		{"vault:/path", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := NewStore(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if file, ok := tt.want.(*FileStore); ok {
+				if f, ok := got.(*FileStore); !ok || f.Path != file.Path {
+					t.Errorf("NewStore() = %#v, want %#v", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("NewStore() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStores(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("secrets.env", "DB_PASSWORD=\"p@ss word\"\n")
+	write("vault/api/token", "s3cr3t\r\nuser: admin\n")
+	t.Setenv("CHECKUP_SECRET_TOKEN", "from-env")
+
+	tests := []struct {
+		name    string
+		store   Store
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{"env", EnvStore{Prefix: "CHECKUP_SECRET_"}, "TOKEN", "from-env", false},
+		{"env missing", EnvStore{Prefix: "CHECKUP_SECRET_"}, "MISSING", "", true},
+		{"file", &FileStore{Path: filepath.Join(dir, "secrets.env")}, "DB_PASSWORD", "p@ss word", false},
+		{"file missing secret", &FileStore{Path: filepath.Join(dir, "secrets.env")}, "TOKEN", "", true},
+		{"missing file", &FileStore{Path: filepath.Join(dir, "missing.env")}, "TOKEN", "", true},
+		{"dir first line", DirStore{Dir: filepath.Join(dir, "vault")}, "api/token", "s3cr3t", false},
+		{"dir outside", DirStore{Dir: filepath.Join(dir, "vault")}, "../secrets.env", "", true},
+		{"dir missing", DirStore{Dir: filepath.Join(dir, "vault")}, "api/missing", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.store.Secret(tt.secret)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Secret() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package scoring
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	StatusWaived  = "waived"
+)
+
+// Treatment of skipped cases in ratings
+const (
+	SkippedExclude = "exclude"
+	SkippedFail    = "fail"
+	SkippedPass    = "pass"
+)
+
+// DefaultSeverity is applied to the cases with no 'severity' set
+const DefaultSeverity = "medium"
+
+var severities = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// Result is a single scored case, SkippedAs defines how it's rated when skipped,
+// waived cases are rated as passed unless they're Unscored
+type Result struct {
+	Category  string
+	Severity  string
+	Weight    int
+	Status    string
+	SkippedAs string
+	Unscored  bool
+}
+
+// Score holds case counters and the weighted rating of a set of results
+type Score struct {
+	All     int     `json:"all"`
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Waived  int     `json:"waived"`
+	Earned  int     `json:"earned"`
+	Max     int     `json:"max"`
+	Rating  float64 `json:"rating"`
+}
+
+// Category is a Score of the results sharing the same category
+type Category struct {
+	Name string `json:"name"`
+	Score
+}
+
+// Summary is an overall Score with the breakdown by categories
+type Summary struct {
+	Score
+	Categories []Category `json:"categories,omitempty"`
+}
+
+func ValidateSeverity(severity string) error {
+	if _, ok := severities[strings.ToLower(severity)]; ok || severity == "" {
+		return nil
+	}
+	return fmt.Errorf("unknown severity '%s', expected one of: low, medium, high, critical", severity)
+}
+
+func ValidateSkipped(policy string) error {
+	switch policy {
+	case "", SkippedExclude, SkippedFail, SkippedPass:
+		return nil
+	}
+	return fmt.Errorf("unknown skipped cases treatment '%s', expected one of: exclude, fail, pass", policy)
+}
+
+// Factor returns the severity multiplier of the case weight
+func Factor(severity string) int {
+	if factor, ok := severities[strings.ToLower(severity)]; ok {
+		return factor
+	}
+	return severities[DefaultSeverity]
+}
+
+func (s *Score) add(r Result) {
+	weight := r.Weight * Factor(r.Severity)
+
+	s.All++
+	switch r.Status {
+	case StatusPassed:
+		s.Passed++
+		s.Max += weight
+		s.Earned += weight
+	case StatusSkipped:
+		s.Skipped++
+		switch r.SkippedAs {
+		case SkippedFail:
+			s.Max += weight
+		case SkippedPass:
+			s.Max += weight
+			s.Earned += weight
+		}
+	case StatusWaived:
+		s.Waived++
+		if !r.Unscored {
+			s.Max += weight
+			s.Earned += weight
+		}
+	default:
+		s.Failed++
+		s.Max += weight
+	}
+
+	s.Rating = 0
+	if s.Max > 0 {
+		s.Rating = 100 * float64(s.Earned) / float64(s.Max)
+	}
+}
+
+// Calculate rates the results, skipped ones are excluded unless their SkippedAs says otherwise
+func Calculate(results []Result) Summary {
+	summary := Summary{}
+	index := map[string]int{}
+
+	for _, r := range results {
+		summary.add(r)
+
+		if r.Category == "" {
+			continue
+		}
+
+		if _, ok := index[r.Category]; !ok {
+			index[r.Category] = len(summary.Categories)
+			summary.Categories = append(summary.Categories, Category{Name: r.Category})
+		}
+		summary.Categories[index[r.Category]].add(r)
+	}
+
+	return summary
+}
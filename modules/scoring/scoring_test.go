@@ -0,0 +1,106 @@
+package scoring
+
+import "testing"
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    Score
+	}{
+		{
+			name:    "no results",
+			results: nil,
+			want:    Score{},
+		},
+		{
+			name: "severity weights",
+			results: []Result{
+				{Weight: 1, Severity: "critical", Status: StatusPassed},
+				{Weight: 1, Severity: "low", Status: StatusFailed},
+			},
+			want: Score{All: 2, Passed: 1, Failed: 1, Earned: 4, Max: 5, Rating: 80},
+		},
+		{
+			name: "default severity is medium",
+			results: []Result{
+				{Weight: 2, Status: StatusPassed},
+				{Weight: 1, Severity: "unknown", Status: StatusFailed},
+			},
+			want: Score{All: 2, Passed: 1, Failed: 1, Earned: 4, Max: 6, Rating: 200.0 / 3},
+		},
+		{
+			name: "skipped cases treatment",
+			results: []Result{
+				{Weight: 1, Status: StatusPassed},
+				{Weight: 1, Status: StatusSkipped},
+				{Weight: 1, Status: StatusSkipped, SkippedAs: SkippedExclude},
+				{Weight: 1, Status: StatusSkipped, SkippedAs: SkippedFail},
+				{Weight: 1, Status: StatusSkipped, SkippedAs: SkippedPass},
+			},
+			want: Score{All: 5, Passed: 1, Skipped: 4, Earned: 4, Max: 6, Rating: 200.0 / 3},
+		},
+		{
+			name: "waived cases",
+			results: []Result{
+				{Weight: 1, Status: StatusWaived},
+				{Weight: 1, Status: StatusWaived, Unscored: true},
+				{Weight: 1, Status: StatusFailed},
+			},
+			want: Score{All: 3, Failed: 1, Waived: 2, Earned: 2, Max: 4, Rating: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Calculate(tt.results).Score; got != tt.want {
+				t.Errorf("Calculate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateCategories(t *testing.T) {
+	summary := Calculate([]Result{
+		{Category: "network", Weight: 1, Status: StatusPassed},
+		{Category: "files", Weight: 1, Status: StatusFailed},
+		{Category: "network", Weight: 1, Status: StatusFailed},
+		{Weight: 1, Status: StatusPassed},
+	})
+
+	want := []Category{
+		{Name: "network", Score: Score{All: 2, Passed: 1, Failed: 1, Earned: 2, Max: 4, Rating: 50}},
+		{Name: "files", Score: Score{All: 1, Failed: 1, Max: 2}},
+	}
+	if len(summary.Categories) != len(want) {
+		t.Fatalf("Categories = %+v, want %+v", summary.Categories, want)
+	}
+	for i := range want {
+		if summary.Categories[i] != want[i] {
+			t.Errorf("Categories[%d] = %+v, want %+v", i, summary.Categories[i], want[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{"empty severity", ValidateSeverity(""), false},
+		{"severity case insensitive", ValidateSeverity("HIGH"), false},
+		{"unknown severity", ValidateSeverity("urgent"), true},
+		{"empty skipped", ValidateSkipped(""), false},
+		{"skipped pass", ValidateSkipped(SkippedPass), false},
+		{"unknown skipped", ValidateSkipped("ignore"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", tt.err, tt.wantErr)
+			}
+		})
+	}
+}
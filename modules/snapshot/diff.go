@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines are the unchanged lines around the changes in the diff hunks
+const contextLines = 3
+
+// maxDiffCells limits the memory of the line matching, bigger changes are shown as replaced blocks
+const maxDiffCells = 4 << 20
+
+// edit is a line of the diff: ' ' unchanged, '-' removed from the snapshot, '+' added in the output
+type edit struct {
+	op   byte
+	text string
+	a, b int
+}
+
+// Diff is the unified diff of the texts, it's empty when they're equal
+func Diff(expected string, actual string, expectedName string, actualName string) string {
+	if expected == actual {
+		return ""
+	}
+
+	edits := diffLines(splitLines(expected), splitLines(actual))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", expectedName, actualName)
+
+	for start := 0; start < len(edits); {
+		// next change
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		from := max(start-contextLines, 0)
+		end := start
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			// unchanged run, the hunk ends when it's longer than the context on both sides
+			run := end
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*contextLines {
+				end = min(end+contextLines, len(edits))
+				break
+			}
+			end = run
+		}
+
+		hunk := edits[from:end]
+		aStart, aCount, bStart, bCount := hunk[0].a, 0, hunk[0].b, 0
+		for _, e := range hunk {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, e := range hunk {
+			fmt.Fprintf(&b, "%c%s\n", e.op, e.text)
+		}
+
+		start = end
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// hunkRange is 'start,count' with 1-based start, or the line before the hunk when it's empty
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines matches the lines by the longest common subsequence, after the common prefix and suffix are cut off
+func diffLines(a []string, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := []edit{}
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{op: ' ', text: a[i], a: i, b: i})
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(ma)+1)*(len(mb)+1) > maxDiffCells {
+		for i, line := range ma {
+			edits = append(edits, edit{op: '-', text: line, a: prefix + i, b: prefix})
+		}
+		for j, line := range mb {
+			edits = append(edits, edit{op: '+', text: line, a: prefix + len(ma), b: prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the common subsequence of ma[i:] and mb[j:]
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				edits = append(edits, edit{op: ' ', text: ma[i], a: prefix + i, b: prefix + j})
+				i, j = i+1, j+1
+			case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+				edits = append(edits, edit{op: '-', text: ma[i], a: prefix + i, b: prefix + j})
+				i++
+			default:
+				edits = append(edits, edit{op: '+', text: mb[j], a: prefix + i, b: prefix + j})
+				j++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		edits = append(edits, edit{op: ' ', text: a[i], a: i, b: j})
+	}
+
+	return edits
+}
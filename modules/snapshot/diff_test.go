@@ -0,0 +1,32 @@
+package snapshot
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		want     string
+	}{
+		{"equal", "a\nb", "a\nb", ""},
+		{"changed line", "a\nb\nc", "a\nB\nc", "--- snap\n+++ output\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c"},
+		{"added line", "a\nb", "a\nb\nc", "--- snap\n+++ output\n@@ -1,2 +1,3 @@\n a\n b\n+c"},
+		{"removed line", "a\nb\nc", "b\nc", "--- snap\n+++ output\n@@ -1,3 +1,2 @@\n-a\n b\n c"},
+		{"from empty", "", "a", "--- snap\n+++ output\n@@ -0,0 +1 @@\n+a"},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve",
+			"--- snap\n+++ output\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.expected, tt.actual, "snap", "output"); got != tt.want {
+				t.Errorf("Diff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
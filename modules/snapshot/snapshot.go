@@ -0,0 +1,122 @@
+// Package snapshot compares the case output with the golden one recorded by the previous runs
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Dir is the directory of snapshots, it's created next to the suite file
+const Dir = "__snapshots__"
+
+// Snapshot states
+const (
+	Recorded = "recorded"
+	Matched  = "matched"
+	Changed  = "changed"
+	Updated  = "updated"
+)
+
+// Normalizer replaces volatile parts of the output, e.g. timestamps or PIDs, before it's compared or recorded
+type Normalizer struct {
+	Pattern string `yaml:"pattern"`
+	Replace string `yaml:"replace"`
+}
+
+// Validate checks the patterns of the normalizers
+func Validate(normalizers []Normalizer) error {
+	for _, n := range normalizers {
+		if n.Pattern == "" {
+			return fmt.Errorf("normalize: 'pattern' is required")
+		}
+		if _, err := regexp.Compile(n.Pattern); err != nil {
+			return fmt.Errorf("normalize: %v", err)
+		}
+	}
+	return nil
+}
+
+// Normalize applies the normalizers one by one, replacements can refer to groups as $1 or ${name}
+func Normalize(text string, normalizers []Normalizer) string {
+	for _, n := range normalizers {
+		text = regexp.MustCompile(n.Pattern).ReplaceAllString(text, n.Replace)
+	}
+	return text
+}
+
+// Path of the case snapshot: __snapshots__/<suite file name>/<case key>.snap next to the suite file
+func Path(suiteFile string, key string) string {
+	dir, name := filepath.Split(suiteFile)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return filepath.Join(dir, Dir, slug(name), slug(key)+".snap")
+}
+
+// slug keeps letters, digits, dots and dashes of the name
+func slug(name string) string {
+	name = regexp.MustCompile(`[^A-Za-z0-9.]+`).ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// Result of the comparison
+type Result struct {
+	File  string `json:"file"`
+	State string `json:"state"`
+	Diff  string `json:"diff,omitempty"`
+}
+
+// Compare checks the output against the snapshot file, the missing snapshot is recorded,
+// the changed one is overwritten when update is set; the error is a Failure with the diff
+func Compare(file string, output string, update bool) (Result, error) {
+	result := Result{File: file}
+
+	data, err := os.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		result.State = Recorded
+		return result, write(file, output)
+	case err != nil:
+		return result, err
+	}
+
+	expected := strings.TrimSuffix(string(data), "\n")
+	if expected == output {
+		result.State = Matched
+		return result, nil
+	}
+
+	result.Diff = Diff(expected, output, file, "output")
+	if update {
+		result.State = Updated
+		return result, write(file, output)
+	}
+
+	result.State = Changed
+	return result, &Failure{Message: fmt.Sprintf("output doesn't match snapshot %s, run with --update-snapshots to accept it:\n%s", file, result.Diff)}
+}
+
+func write(file string, output string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(output+"\n"), 0644)
+}
+
+// Failure is the result of the changed output, it exits with 1 as a failed script would
+type Failure struct {
+	Message string
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+func (f *Failure) ExitCode() int {
+	return 1
+}
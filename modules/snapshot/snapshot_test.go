@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizers := []Normalizer{
+		{Pattern: `\d{4}-\d{2}-\d{2}`, Replace: "<date>"},
+		{Pattern: `pid=(?P<name>\w+):\d+`, Replace: "pid=${name}:<pid>"},
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"started 2026-03-10", "started <date>"},
+		{"pid=sshd:1234 pid=cron:56", "pid=sshd:<pid> pid=cron:<pid>"},
+		{"nothing volatile", "nothing volatile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Normalize(tt.text, normalizers); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		normalizers []Normalizer
+		wantErr     bool
+	}{
+		{"none", nil, false},
+		{"valid", []Normalizer{{Pattern: `\d+`, Replace: "N"}}, false},
+		{"no pattern", []Normalizer{{Replace: "N"}}, true},
+		{"invalid pattern", []Normalizer{{Pattern: `(\d+`}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.normalizers); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		suiteFile string
+		key       string
+		want      string
+	}{
+		{"suites/web.yaml", "Check Version", "suites/__snapshots__/web/check-version.snap"},
+		{"web.yml", "1.2.3 nginx: config", "__snapshots__/web/1.2.3-nginx-config.snap"},
+		{"web.yml", "?!", "__snapshots__/web/_.snap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := Path(tt.suiteFile, tt.key); got != filepath.FromSlash(tt.want) {
+				t.Errorf("Path() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	file := filepath.Join(t.TempDir(), Dir, "suite", "case.snap")
+
+	tests := []struct {
+		name      string
+		output    string
+		update    bool
+		wantState string
+		wantErr   bool
+		wantSaved string
+	}{
+		{"recorded", "v1", false, Recorded, false, "v1\n"},
+		{"matched", "v1", false, Matched, false, "v1\n"},
+		{"changed", "v2", false, Changed, true, "v1\n"},
+		{"updated", "v2", true, Updated, false, "v2\n"},
+		{"matched after update", "v2", false, Matched, false, "v2\n"},
+	}
+
+	// the cases depend on the snapshot saved by the previous ones
+	for _, tt := range tests {
+		result, err := Compare(file, tt.output, tt.update)
+		if (err != nil) != tt.wantErr || result.State != tt.wantState {
+			t.Fatalf("%s: Compare() = %s, %v, want %s, wantErr %v", tt.name, result.State, err, tt.wantState, tt.wantErr)
+		}
+		if (result.Diff != "") != (tt.wantState == Changed || tt.wantState == Updated) {
+			t.Errorf("%s: Diff = %q", tt.name, result.Diff)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.wantSaved {
+			t.Errorf("%s: snapshot = %q, want %q", tt.name, data, tt.wantSaved)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package waivers
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const dateLayout = "2006-01-02"
+
+// Waiver accepts failures of the cases matched by title, control id or tag
+type Waiver struct {
+	Case          string `yaml:"case" json:"case,omitempty"`
+	Control       string `yaml:"control" json:"control,omitempty"`
+	Tag           string `yaml:"tag" json:"tag,omitempty"`
+	Justification string `yaml:"justification" json:"justification"`
+	Owner         string `yaml:"owner" json:"owner,omitempty"`
+	Expires       string `yaml:"expires" json:"expires,omitempty"`
+	Unscored      bool   `yaml:"unscored" json:"unscored,omitempty"`
+
+	// Matched is the number of failures the waiver is applied to
+	Matched int  `yaml:"-" json:"matched"`
+	Expired bool `yaml:"-" json:"expired,omitempty"`
+
+	expires time.Time
+}
+
+type waiversFile struct {
+	Waivers []*Waiver `yaml:"waivers"`
+}
+
+// Load reads waivers file, expiration is checked against 'now'
+func Load(fileName string, now time.Time) ([]*Waiver, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var f waiversFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot recognize waivers structure in file: %s", fileName)
+	}
+
+	for i, w := range f.Waivers {
+		if w.Case == "" && w.Control == "" && w.Tag == "" {
+			return nil, fmt.Errorf("%s: waiver #%d: one of 'case', 'control' or 'tag' is required", fileName, i+1)
+		}
+
+		if strings.TrimSpace(w.Justification) == "" {
+			return nil, fmt.Errorf("%s: waiver '%s': 'justification' is required", fileName, w)
+		}
+
+		if w.Expires != "" {
+			w.expires, err = time.Parse(dateLayout, w.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("%s: waiver '%s': 'expires' should be in YYYY-MM-DD format", fileName, w)
+			}
+			// a waiver is valid till the end of its expiration day
+			w.Expired = !now.Before(w.expires.AddDate(0, 0, 1))
+		}
+	}
+
+	return f.Waivers, nil
+}
+
+func (w *Waiver) String() string {
+	selectors := []string{}
+	if w.Case != "" {
+		selectors = append(selectors, "case: "+w.Case)
+	}
+	if w.Control != "" {
+		selectors = append(selectors, "control: "+w.Control)
+	}
+	if w.Tag != "" {
+		selectors = append(selectors, "tag: "+w.Tag)
+	}
+	return strings.Join(selectors, ", ")
+}
+
+// Matches checks every selector set in the waiver, the case title and control id may be glob patterns
+func (w *Waiver) Matches(title string, control string, tags []string) bool {
+	if w.Case != "" {
+		if matched, _ := path.Match(w.Case, title); !matched && w.Case != title {
+			return false
+		}
+	}
+
+	if w.Control != "" {
+		if matched, _ := path.Match(w.Control, control); !matched || control == "" {
+			return false
+		}
+	}
+
+	if w.Tag != "" {
+		found := false
+		for _, tag := range tags {
+			if tag == w.Tag {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find returns the first matching waiver, active waivers take precedence over expired ones;
+// the caller counts the match in 'Matched' when the waiver is applied to a failure
+func Find(waivers []*Waiver, title string, control string, tags []string) *Waiver {
+	var expired *Waiver
+
+	for _, w := range waivers {
+		if !w.Matches(title, control, tags) {
+			continue
+		}
+
+		if !w.Expired {
+			return w
+		}
+		if expired == nil {
+			expired = w
+		}
+	}
+
+	return expired
+}
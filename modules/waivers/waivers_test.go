@@ -0,0 +1,126 @@
+package waivers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeWaivers(t *testing.T, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "waivers.yml")
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestLoadExpiry(t *testing.T) {
+	fileName := writeWaivers(t, `
+waivers:
+- case: no expiration
+  justification: accepted
+- case: expires today
+  justification: accepted
+  expires: 2026-03-10
+- case: expired yesterday
+  justification: accepted
+  expires: 2026-03-09
+`)
+	now := time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)
+
+	loaded, err := Load(fileName, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"no expiration": false, "expires today": false, "expired yesterday": true}
+	for _, w := range loaded {
+		if w.Expired != want[w.Case] {
+			t.Errorf("waiver '%s': Expired = %v, want %v", w.Case, w.Expired, want[w.Case])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no selector", "waivers:\n- justification: accepted\n"},
+		{"no justification", "waivers:\n- case: a\n"},
+		{"blank justification", "waivers:\n- case: a\n  justification: '  '\n"},
+		{"invalid date", "waivers:\n- case: a\n  justification: accepted\n  expires: 10/03/2026\n"},
+		{"invalid structure", "waivers: {case: a}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(writeWaivers(t, tt.content), time.Now()); err == nil {
+				t.Error("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		waiver  Waiver
+		title   string
+		control string
+		tags    []string
+		want    bool
+	}{
+		{"exact title", Waiver{Case: "Ensure /tmp is mounted"}, "Ensure /tmp is mounted", "", nil, true},
+		{"title glob", Waiver{Case: "Ensure * is enabled"}, "Ensure chronyd is enabled", "", nil, true},
+		{"title with glob characters", Waiver{Case: "Check [a]"}, "Check [a]", "", nil, true},
+		{"other title", Waiver{Case: "Ensure /tmp is mounted"}, "Ensure /var is mounted", "", nil, false},
+		{"control glob", Waiver{Control: "1.1.*"}, "any", "1.1.2", nil, true},
+		{"control required", Waiver{Control: "*"}, "any", "", nil, false},
+		{"tag", Waiver{Tag: "network"}, "any", "", []string{"files", "network"}, true},
+		{"missing tag", Waiver{Tag: "network"}, "any", "", []string{"files"}, false},
+		{"all selectors", Waiver{Case: "a", Control: "1.*", Tag: "x"}, "a", "2.1", []string{"x"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.waiver.Matches(tt.title, tt.control, tt.tags); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	expired := &Waiver{Case: "a", Expired: true}
+	active := &Waiver{Case: "a*"}
+	other := &Waiver{Case: "b"}
+
+	tests := []struct {
+		name    string
+		waivers []*Waiver
+		title   string
+		want    *Waiver
+	}{
+		{"active over expired", []*Waiver{expired, active, other}, "a", active},
+		{"expired when there's no active one", []*Waiver{expired, other}, "a", expired},
+		{"no match", []*Waiver{expired, active}, "b", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Find(tt.waivers, tt.title, "", nil); got != tt.want {
+				t.Errorf("Find() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// matches are counted when the waiver is applied to a failure, not when it's found
+	for _, w := range []*Waiver{expired, active, other} {
+		if w.Matched != 0 {
+			t.Errorf("waiver '%s': Matched = %d, want 0", w, w.Matched)
+		}
+	}
+}